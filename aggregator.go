@@ -0,0 +1,539 @@
+package indexer
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sschiz/indexer/ticker"
+)
+
+var (
+	ErrNoSamples         = errors.New("no samples")
+	ErrInvalidTrimRatio  = errors.New("invalid trim ratio")
+	ErrInvalidAggregator = errors.New("invalid aggregator")
+	ErrInvalidHalfLife   = errors.New("invalid half-life")
+	ErrOverflow          = errors.New("value overflows float64")
+)
+
+// Sample is a single price observation of a ticker.
+type Sample struct {
+	Price  ticker.Decimal
+	Time   time.Time
+	Volume ticker.Decimal // zero if unknown
+	Source string
+	Weight ticker.Decimal // weight of the source, zero means weight of one
+
+	raw        string // price as collected
+	zeroVolume bool   // volume is collected and it is zero
+}
+
+// weight returns weight of the sample.
+func (s Sample) weight() ticker.Decimal {
+	if s.Weight.IsZero() {
+		return one
+	}
+
+	return s.Weight
+}
+
+var one = ticker.NewDecimal(1, 0)
+
+// Aggregator aggregates samples of a single ticker into an index value.
+type Aggregator interface {
+	// Add adds sample to the aggregate.
+	Add(s Sample)
+	// Value returns aggregated value.
+	// ErrNoSamples is returned if nothing was added since the last Reset.
+	Value() (ticker.Decimal, error)
+	// Reset discards all added samples.
+	Reset()
+}
+
+// Advancer is implemented by aggregators which value depends on the current time.
+// Indexer calls Advance with the tick time before Value.
+type Advancer interface {
+	Advance(t time.Time)
+}
+
+// Weigher is implemented by aggregators which value is a weighted mean of samples
+// with weights known when samples are added, e.g. Mean and VWAP.
+// Indexer uses it to report effective weights of prices in the index.
+type Weigher interface {
+	// Weigh returns weight of the sample in the aggregate.
+	Weigh(s Sample) ticker.Decimal
+	// TotalWeight returns total weight of added samples.
+	TotalWeight() ticker.Decimal
+}
+
+// AggregatorFactory returns new Aggregator instance.
+// Indexer calls it once per ticker.
+type AggregatorFactory func() Aggregator
+
+// Mean calculates weighted arithmetic mean of samples.
+type Mean struct {
+	sum    ticker.Decimal
+	weight ticker.Decimal
+}
+
+// NewMean returns new Mean instance.
+func NewMean() *Mean {
+	return &Mean{}
+}
+
+// Add adds sample to the mean.
+func (m *Mean) Add(s Sample) {
+	w := m.Weigh(s)
+
+	m.sum = m.sum.Add(s.Price.Mul(w))
+	m.weight = m.weight.Add(w)
+}
+
+// Value returns mean of added samples.
+func (m *Mean) Value() (ticker.Decimal, error) {
+	if m.weight.IsZero() {
+		return ticker.Decimal{}, ErrNoSamples
+	}
+
+	return m.sum.Quo(m.weight), nil
+}
+
+// Weigh returns weight of the sample source.
+func (m *Mean) Weigh(s Sample) ticker.Decimal {
+	return s.weight()
+}
+
+// TotalWeight returns total weight of added samples.
+func (m *Mean) TotalWeight() ticker.Decimal {
+	return m.weight
+}
+
+// Reset resets Mean.
+func (m *Mean) Reset() {
+	*m = Mean{}
+}
+
+// Median calculates weighted median of samples.
+// If the cumulative weight splits exactly in half between two prices, their mean is returned.
+type Median struct {
+	samples []Sample
+}
+
+// NewMedian returns new Median instance.
+func NewMedian() *Median {
+	return &Median{}
+}
+
+// Add adds sample to the median.
+func (m *Median) Add(s Sample) {
+	m.samples = append(m.samples, s)
+}
+
+// Value returns median of added samples.
+func (m *Median) Value() (ticker.Decimal, error) {
+	if len(m.samples) == 0 {
+		return ticker.Decimal{}, ErrNoSamples
+	}
+
+	sorted := sortedCopy(m.samples)
+
+	var total ticker.Decimal
+	for _, s := range sorted {
+		total = total.Add(s.weight())
+	}
+
+	half := total.Quo(ticker.NewDecimal(2, 0))
+
+	var cum ticker.Decimal
+	for j, s := range sorted {
+		cum = cum.Add(s.weight())
+
+		switch c := cum.Cmp(half); {
+		case c > 0:
+			return s.Price, nil
+		case c == 0 && j+1 < len(sorted):
+			return s.Price.Add(sorted[j+1].Price).Quo(ticker.NewDecimal(2, 0)), nil
+		}
+	}
+
+	return sorted[len(sorted)-1].Price, nil
+}
+
+// Reset resets Median.
+func (m *Median) Reset() {
+	m.samples = m.samples[:0]
+}
+
+// TrimmedMean calculates weighted mean of samples
+// after discarding the given ratio of the lowest and the highest ones.
+type TrimmedMean struct {
+	ratio   float64
+	samples []Sample
+}
+
+// NewTrimmedMean returns new TrimmedMean instance.
+// Ratio is a share of samples discarded from each end, it must be in [0, 0.5).
+func NewTrimmedMean(ratio float64) (*TrimmedMean, error) {
+	if ratio < 0 || ratio >= 0.5 || math.IsNaN(ratio) {
+		return nil, ErrInvalidTrimRatio
+	}
+
+	return &TrimmedMean{ratio: ratio}, nil
+}
+
+// Add adds sample to the trimmed mean.
+func (m *TrimmedMean) Add(s Sample) {
+	m.samples = append(m.samples, s)
+}
+
+// Value returns trimmed mean of added samples.
+func (m *TrimmedMean) Value() (ticker.Decimal, error) {
+	if len(m.samples) == 0 {
+		return ticker.Decimal{}, ErrNoSamples
+	}
+
+	sorted := sortedCopy(m.samples)
+	trim := int(float64(len(sorted)) * m.ratio)
+
+	var mean Mean
+	for _, s := range sorted[trim : len(sorted)-trim] {
+		mean.Add(s)
+	}
+
+	return mean.Value()
+}
+
+// Reset resets TrimmedMean.
+func (m *TrimmedMean) Reset() {
+	m.samples = m.samples[:0]
+}
+
+// Min returns the lowest sample.
+type Min struct {
+	min ticker.Decimal
+	set bool
+}
+
+// NewMin returns new Min instance.
+func NewMin() *Min {
+	return &Min{}
+}
+
+// Add adds sample to the Min.
+func (m *Min) Add(s Sample) {
+	if !m.set || s.Price.Cmp(m.min) < 0 {
+		m.min = s.Price
+		m.set = true
+	}
+}
+
+// Value returns the lowest added sample.
+func (m *Min) Value() (ticker.Decimal, error) {
+	if !m.set {
+		return ticker.Decimal{}, ErrNoSamples
+	}
+
+	return m.min, nil
+}
+
+// Reset resets Min.
+func (m *Min) Reset() {
+	*m = Min{}
+}
+
+// Max returns the highest sample.
+type Max struct {
+	max ticker.Decimal
+	set bool
+}
+
+// NewMax returns new Max instance.
+func NewMax() *Max {
+	return &Max{}
+}
+
+// Add adds sample to the Max.
+func (m *Max) Add(s Sample) {
+	if !m.set || s.Price.Cmp(m.max) > 0 {
+		m.max = s.Price
+		m.set = true
+	}
+}
+
+// Value returns the highest added sample.
+func (m *Max) Value() (ticker.Decimal, error) {
+	if !m.set {
+		return ticker.Decimal{}, ErrNoSamples
+	}
+
+	return m.max, nil
+}
+
+// Reset resets Max.
+func (m *Max) Reset() {
+	*m = Max{}
+}
+
+// Last returns the most recent sample.
+// Samples with equal time are resolved in favor of the last added one.
+type Last struct {
+	last Sample
+	set  bool
+}
+
+// NewLast returns new Last instance.
+func NewLast() *Last {
+	return &Last{}
+}
+
+// Add adds sample to the Last.
+func (l *Last) Add(s Sample) {
+	if !l.set || !s.Time.Before(l.last.Time) {
+		l.last = s
+		l.set = true
+	}
+}
+
+// Value returns the most recent added sample.
+func (l *Last) Value() (ticker.Decimal, error) {
+	if !l.set {
+		return ticker.Decimal{}, ErrNoSamples
+	}
+
+	return l.last.Price, nil
+}
+
+// Reset resets Last.
+func (l *Last) Reset() {
+	*l = Last{}
+}
+
+// EMA calculates exponential moving average of samples.
+// Weight of each sample halves every half-life elapsed
+// between its time and the time of the most recent sample,
+// so only the aggregate is kept in memory.
+// Decay factors are irrational, so unlike other aggregators EMA is calculated in float64.
+// Weight of the sample source multiplies its decayed weight.
+// Samples which do not fit float64 are ignored.
+type EMA struct {
+	halfLife time.Duration
+	sum      float64
+	weight   float64
+	last     time.Time
+}
+
+// NewEMA returns new EMA instance.
+func NewEMA(halfLife time.Duration) (*EMA, error) {
+	if halfLife <= 0 {
+		return nil, ErrInvalidHalfLife
+	}
+
+	return &EMA{halfLife: halfLife}, nil
+}
+
+// Add adds sample to the EMA.
+func (e *EMA) Add(s Sample) {
+	p, w := s.Price.Float64(), s.weight().Float64()
+	if !finite(p * w) {
+		return
+	}
+
+	if e.weight == 0 {
+		e.sum = p * w
+		e.weight = w
+		e.last = s.Time
+
+		return
+	}
+
+	if s.Time.Before(e.last) {
+		// Out of order sample is decayed on its own.
+		w *= e.decay(e.last.Sub(s.Time))
+		e.sum += p * w
+		e.weight += w
+
+		return
+	}
+
+	d := e.decay(s.Time.Sub(e.last))
+	e.sum = e.sum*d + p*w
+	e.weight = e.weight*d + w
+	e.last = s.Time
+}
+
+// Value returns exponential moving average of added samples.
+// ErrOverflow is returned if the sum of samples overflows float64.
+func (e *EMA) Value() (ticker.Decimal, error) {
+	if e.weight == 0 {
+		return ticker.Decimal{}, ErrNoSamples
+	}
+
+	v := e.sum / e.weight
+	if !finite(v) {
+		return ticker.Decimal{}, ErrOverflow
+	}
+
+	return ticker.NewDecimalFromFloat(v), nil
+}
+
+// Reset resets EMA.
+func (e *EMA) Reset() {
+	*e = EMA{halfLife: e.halfLife}
+}
+
+func (e *EMA) decay(elapsed time.Duration) float64 {
+	return math.Exp2(-float64(elapsed) / float64(e.halfLife))
+}
+
+func finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// VWAP calculates volume-weighted average price of samples.
+// Samples without volume are weighted equally with volume of one,
+// prices collected with zero volume have no weight.
+// Volume is multiplied by weight of the sample source.
+type VWAP struct {
+	sum    ticker.Decimal
+	volume ticker.Decimal
+}
+
+// NewVWAP returns new VWAP instance.
+func NewVWAP() *VWAP {
+	return &VWAP{}
+}
+
+// Add adds sample to the VWAP.
+func (v *VWAP) Add(s Sample) {
+	volume := v.Weigh(s)
+
+	v.sum = v.sum.Add(s.Price.Mul(volume))
+	v.volume = v.volume.Add(volume)
+}
+
+// Value returns volume-weighted average price of added samples.
+func (v *VWAP) Value() (ticker.Decimal, error) {
+	if v.volume.IsZero() {
+		return ticker.Decimal{}, ErrNoSamples
+	}
+
+	return v.sum.Quo(v.volume), nil
+}
+
+// Weigh returns volume of the sample multiplied by weight of its source.
+func (v *VWAP) Weigh(s Sample) ticker.Decimal {
+	volume := s.Volume
+	if volume.IsZero() && !s.zeroVolume {
+		volume = one
+	}
+
+	return volume.Mul(s.weight())
+}
+
+// TotalWeight returns total weighted volume of added samples.
+func (v *VWAP) TotalWeight() ticker.Decimal {
+	return v.volume
+}
+
+// Reset resets VWAP.
+func (v *VWAP) Reset() {
+	*v = VWAP{}
+}
+
+// TWAP calculates time-weighted average price of samples.
+// Each price is weighted by duration it remained the most recent one,
+// the most recent price lasts until the time passed to Advance.
+// Durations are multiplied by weight of the sample source.
+// Samples with equal time are averaged, samples older than the most recent one are ignored.
+type TWAP struct {
+	pending []Sample
+	end     time.Time
+
+	sum    ticker.Decimal // sum of prices multiplied by their weighted durations in nanoseconds
+	weight ticker.Decimal // sum of weighted durations
+
+	curSum    ticker.Decimal // weighted sum of the most recent prices
+	curTime   time.Time
+	curWeight ticker.Decimal // weight of samples at curTime
+}
+
+// NewTWAP returns new TWAP instance.
+func NewTWAP() *TWAP {
+	return &TWAP{}
+}
+
+// Add adds sample to the TWAP.
+func (w *TWAP) Add(s Sample) {
+	w.pending = append(w.pending, s)
+}
+
+// Advance sets time until which the most recent price lasts.
+func (w *TWAP) Advance(t time.Time) {
+	if t.After(w.end) {
+		w.end = t
+	}
+}
+
+// Value returns time-weighted average price of added samples.
+func (w *TWAP) Value() (ticker.Decimal, error) {
+	w.fold()
+
+	if w.curWeight.IsZero() {
+		return ticker.Decimal{}, ErrNoSamples
+	}
+
+	sum, weight := w.sum, w.weight
+	if w.end.After(w.curTime) {
+		d := ticker.NewDecimal(int64(w.end.Sub(w.curTime)), 0)
+		sum = sum.Add(w.curSum.Mul(d))
+		weight = weight.Add(w.curWeight.Mul(d))
+	}
+
+	if weight.IsZero() {
+		return w.curSum.Quo(w.curWeight), nil
+	}
+
+	return sum.Quo(weight), nil
+}
+
+// Reset resets TWAP.
+func (w *TWAP) Reset() {
+	*w = TWAP{pending: w.pending[:0]}
+}
+
+func (w *TWAP) fold() {
+	sort.SliceStable(w.pending, func(i, j int) bool {
+		return w.pending[i].Time.Before(w.pending[j].Time)
+	})
+
+	for _, s := range w.pending {
+		switch {
+		case w.curWeight.IsZero() || s.Time.After(w.curTime):
+			if !w.curWeight.IsZero() {
+				d := ticker.NewDecimal(int64(s.Time.Sub(w.curTime)), 0)
+				w.sum = w.sum.Add(w.curSum.Mul(d))
+				w.weight = w.weight.Add(w.curWeight.Mul(d))
+			}
+
+			w.curSum = s.Price.Mul(s.weight())
+			w.curTime = s.Time
+			w.curWeight = s.weight()
+		case s.Time.Equal(w.curTime):
+			w.curSum = w.curSum.Add(s.Price.Mul(s.weight()))
+			w.curWeight = w.curWeight.Add(s.weight())
+		}
+	}
+
+	w.pending = w.pending[:0]
+}
+
+func sortedCopy(samples []Sample) []Sample {
+	sorted := make([]Sample, len(samples))
+	copy(sorted, samples)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Price.Cmp(sorted[j].Price) < 0
+	})
+
+	return sorted
+}
@@ -0,0 +1,531 @@
+package indexer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dec(f float64) ticker.Decimal {
+	return ticker.NewDecimalFromFloat(f)
+}
+
+func addPrices(a Aggregator, prices ...float64) {
+	for _, p := range prices {
+		a.Add(Sample{Price: dec(p)})
+	}
+}
+
+func TestMean_Add(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []float64
+		sum    string
+		weight string
+	}{
+		{
+			name:   "positive",
+			args:   []float64{1, 2, 3, 4},
+			sum:    "10",
+			weight: "4",
+		},
+		{
+			name:   "negative",
+			args:   []float64{-1, -2, -3, -4},
+			sum:    "-10",
+			weight: "4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMean()
+			addPrices(m, tt.args...)
+
+			assert.Equal(t, tt.sum, m.sum.String())
+			assert.Equal(t, tt.weight, m.weight.String())
+		})
+	}
+}
+
+func TestMean_Value(t *testing.T) {
+	tests := []struct {
+		name string
+		args []float64
+		want float64
+	}{
+		{
+			name: "positive",
+			args: []float64{2, 4, 8, 10},
+			want: 6,
+		},
+		{
+			name: "negative",
+			args: []float64{-2, -4, -8, -10},
+			want: -6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMean()
+			addPrices(m, tt.args...)
+
+			got, err := m.Value()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Float64())
+		})
+	}
+}
+
+func TestNewTrimmedMean(t *testing.T) {
+	for _, ratio := range []float64{-0.1, 0.5, 1} {
+		got, err := NewTrimmedMean(ratio)
+		require.Nil(t, got)
+		assert.ErrorIs(t, err, ErrInvalidTrimRatio)
+	}
+
+	got, err := NewTrimmedMean(0.1)
+	require.NoError(t, err)
+	assert.Equal(t, &TrimmedMean{ratio: 0.1}, got)
+}
+
+func TestAggregators(t *testing.T) {
+	trimmed, err := NewTrimmedMean(0.2)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		agg  Aggregator
+		args []float64
+		want float64
+	}{
+		{
+			name: "median odd",
+			agg:  NewMedian(),
+			args: []float64{5, 1, 3},
+			want: 3,
+		},
+		{
+			name: "median even",
+			agg:  NewMedian(),
+			args: []float64{4, 1, 3, 2},
+			want: 2.5,
+		},
+		{
+			name: "trimmed mean",
+			agg:  trimmed,
+			args: []float64{100, 2, 3, 4, 0},
+			want: 3,
+		},
+		{
+			name: "min",
+			agg:  NewMin(),
+			args: []float64{3, 1, 2},
+			want: 1,
+		},
+		{
+			name: "max",
+			agg:  NewMax(),
+			args: []float64{3, 1, 2},
+			want: 3,
+		},
+		{
+			name: "last",
+			agg:  NewLast(),
+			args: []float64{3, 1, 2},
+			want: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.agg.Value()
+			require.ErrorIs(t, err, ErrNoSamples)
+
+			addPrices(tt.agg, tt.args...)
+
+			got, err := tt.agg.Value()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Float64())
+
+			tt.agg.Reset()
+
+			_, err = tt.agg.Value()
+			assert.ErrorIs(t, err, ErrNoSamples)
+		})
+	}
+}
+
+func TestAggregators_weighted(t *testing.T) {
+	now := time.Now()
+
+	trimmed, err := NewTrimmedMean(0.2)
+	require.NoError(t, err)
+
+	ema, err := NewEMA(time.Minute)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		agg     Aggregator
+		samples []Sample
+		want    float64
+	}{
+		{
+			name: "mean",
+			agg:  NewMean(),
+			samples: []Sample{
+				{Price: dec(10), Weight: dec(3)},
+				{Price: dec(20)},
+			},
+			want: 12.5,
+		},
+		{
+			name: "median",
+			agg:  NewMedian(),
+			samples: []Sample{
+				{Price: dec(1)},
+				{Price: dec(2)},
+				{Price: dec(10), Weight: dec(3)},
+			},
+			want: 10,
+		},
+		{
+			name: "median exact half",
+			agg:  NewMedian(),
+			samples: []Sample{
+				{Price: dec(1), Weight: dec(2)},
+				{Price: dec(2)},
+				{Price: dec(3)},
+			},
+			want: 1.5,
+		},
+		{
+			name: "trimmed mean",
+			agg:  trimmed,
+			samples: []Sample{
+				{Price: dec(100)},
+				{Price: dec(2), Weight: dec(3)},
+				{Price: dec(6)},
+				{Price: dec(4)},
+				{Price: dec(0)},
+			},
+			want: 3.2,
+		},
+		{
+			name: "ema",
+			agg:  ema,
+			samples: []Sample{
+				{Price: dec(10), Time: now, Weight: dec(3)},
+				{Price: dec(20), Time: now},
+			},
+			want: 12.5,
+		},
+		{
+			name: "vwap",
+			agg:  NewVWAP(),
+			samples: []Sample{
+				{Price: dec(10), Volume: dec(1), Weight: dec(2)},
+				{Price: dec(40), Volume: dec(1)},
+			},
+			want: 20,
+		},
+		{
+			name: "twap",
+			agg:  NewTWAP(),
+			samples: []Sample{
+				{Price: dec(10), Time: now, Weight: dec(3)},
+				{Price: dec(20), Time: now},
+			},
+			want: 12.5,
+		},
+		{
+			name: "max ignores weight",
+			agg:  NewMax(),
+			samples: []Sample{
+				{Price: dec(10), Weight: dec(100)},
+				{Price: dec(20)},
+			},
+			want: 20,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, s := range tt.samples {
+				tt.agg.Add(s)
+			}
+
+			if a, ok := tt.agg.(Advancer); ok {
+				a.Advance(now)
+			}
+
+			got, err := tt.agg.Value()
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got.Float64(), 1e-9)
+		})
+	}
+}
+
+func TestLast_Add(t *testing.T) {
+	now := time.Now()
+
+	l := NewLast()
+	l.Add(Sample{Price: dec(1), Time: now})
+	l.Add(Sample{Price: dec(2), Time: now.Add(-time.Second)})
+
+	got, err := l.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "1", got.String())
+}
+
+func TestNewEMA(t *testing.T) {
+	got, err := NewEMA(0)
+	require.Nil(t, got)
+	assert.ErrorIs(t, err, ErrInvalidHalfLife)
+
+	got, err = NewEMA(time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, &EMA{halfLife: time.Minute}, got)
+}
+
+func TestEMA_Value(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		samples []Sample
+		want    float64
+	}{
+		{
+			name: "single",
+			samples: []Sample{
+				{Price: dec(10), Time: now},
+			},
+			want: 10,
+		},
+		{
+			name: "one half-life",
+			samples: []Sample{
+				{Price: dec(10), Time: now},
+				{Price: dec(20), Time: now.Add(time.Minute)},
+			},
+			want: 25 / 1.5,
+		},
+		{
+			name: "same time",
+			samples: []Sample{
+				{Price: dec(10), Time: now},
+				{Price: dec(20), Time: now},
+			},
+			want: 15,
+		},
+		{
+			name: "out of order",
+			samples: []Sample{
+				{Price: dec(20), Time: now.Add(time.Minute)},
+				{Price: dec(10), Time: now},
+			},
+			want: 25 / 1.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewEMA(time.Minute)
+			require.NoError(t, err)
+
+			for _, s := range tt.samples {
+				e.Add(s)
+			}
+
+			got, err := e.Value()
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got.Float64(), 1e-9)
+
+			e.Reset()
+
+			_, err = e.Value()
+			assert.ErrorIs(t, err, ErrNoSamples)
+		})
+	}
+}
+
+func TestEMA_overflow(t *testing.T) {
+	huge, err := ticker.ParseDecimal("1e400")
+	require.NoError(t, err)
+
+	e, err := NewEMA(time.Minute)
+	require.NoError(t, err)
+
+	e.Add(Sample{Price: huge})
+
+	_, err = e.Value()
+	require.ErrorIs(t, err, ErrNoSamples)
+
+	e.Add(Sample{Price: dec(10)})
+
+	got, err := e.Value()
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, got.Float64())
+
+	e.Add(Sample{Price: dec(math.MaxFloat64)})
+	e.Add(Sample{Price: dec(math.MaxFloat64)})
+
+	_, err = e.Value()
+	assert.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestVWAP_Value(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []Sample
+		want    float64
+	}{
+		{
+			name: "with volume",
+			samples: []Sample{
+				{Price: dec(10), Volume: dec(3)},
+				{Price: dec(20), Volume: dec(1)},
+			},
+			want: 12.5,
+		},
+		{
+			name: "without volume",
+			samples: []Sample{
+				{Price: dec(10)},
+				{Price: dec(20)},
+			},
+			want: 15,
+		},
+		{
+			name: "mixed",
+			samples: []Sample{
+				{Price: dec(10), Volume: dec(2)},
+				{Price: dec(40)},
+			},
+			want: 20,
+		},
+		{
+			name: "zero volume",
+			samples: []Sample{
+				{Price: dec(10), Volume: dec(100)},
+				{Price: dec(1000), zeroVolume: true},
+			},
+			want: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVWAP()
+
+			_, err := v.Value()
+			require.ErrorIs(t, err, ErrNoSamples)
+
+			for _, s := range tt.samples {
+				v.Add(s)
+			}
+
+			got, err := v.Value()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Float64())
+
+			v.Reset()
+
+			_, err = v.Value()
+			assert.ErrorIs(t, err, ErrNoSamples)
+		})
+	}
+}
+
+func TestTWAP_Value(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		samples []Sample
+		end     time.Time
+		want    float64
+	}{
+		{
+			name: "single",
+			samples: []Sample{
+				{Price: dec(10), Time: now},
+			},
+			end:  now.Add(time.Second),
+			want: 10,
+		},
+		{
+			name: "weighted by duration",
+			samples: []Sample{
+				{Price: dec(30), Time: now.Add(3 * time.Second)},
+				{Price: dec(10), Time: now},
+			},
+			end:  now.Add(4 * time.Second),
+			want: 15,
+		},
+		{
+			name: "burst",
+			samples: []Sample{
+				{Price: dec(10), Time: now},
+				{Price: dec(100), Time: now.Add(time.Millisecond)},
+				{Price: dec(100), Time: now.Add(2 * time.Millisecond)},
+				{Price: dec(10), Time: now.Add(3 * time.Millisecond)},
+			},
+			end:  now.Add(time.Second),
+			want: 10 + 90*0.002,
+		},
+		{
+			name: "equal time",
+			samples: []Sample{
+				{Price: dec(10), Time: now},
+				{Price: dec(20), Time: now},
+			},
+			end:  now,
+			want: 15,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewTWAP()
+
+			_, err := w.Value()
+			require.ErrorIs(t, err, ErrNoSamples)
+
+			for _, s := range tt.samples {
+				w.Add(s)
+			}
+			w.Advance(tt.end)
+
+			got, err := w.Value()
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got.Float64(), 1e-9)
+
+			w.Reset()
+
+			_, err = w.Value()
+			assert.ErrorIs(t, err, ErrNoSamples)
+		})
+	}
+}
+
+func TestTWAP_Add(t *testing.T) {
+	now := time.Now()
+
+	w := NewTWAP()
+	w.Add(Sample{Price: dec(10), Time: now})
+	w.Advance(now.Add(time.Second))
+
+	got, err := w.Value()
+	require.NoError(t, err)
+	require.Equal(t, "10", got.String())
+
+	// Superseded sample is ignored.
+	w.Add(Sample{Price: dec(1000), Time: now.Add(-time.Second)})
+	w.Add(Sample{Price: dec(20), Time: now.Add(time.Second)})
+	w.Advance(now.Add(2 * time.Second))
+
+	got, err = w.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "15", got.String())
+}
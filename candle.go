@@ -0,0 +1,51 @@
+package indexer
+
+import (
+	"time"
+
+	"github.com/sschiz/indexer/ticker"
+)
+
+// CandleHandler is called for each ticker which had prices during the interval.
+type CandleHandler func(ticker.Candle)
+
+// WithCandleHandler sets handler of OHLC candles.
+// Candles are built from prices collected at the tick regardless of the window.
+// Open and close prices are the earliest and the latest ones by their time.
+func WithCandleHandler(handle CandleHandler) Option {
+	return func(i *Indexer) {
+		i.handleCandle = handle
+	}
+}
+
+func (i *Indexer) candle(tk ticker.Ticker, samples []Sample, t time.Time) ticker.Candle {
+	open, high, low, closing := samples[0], samples[0], samples[0], samples[0]
+
+	for _, s := range samples[1:] {
+		if s.Time.Before(open.Time) {
+			open = s
+		}
+
+		if !s.Time.Before(closing.Time) {
+			closing = s
+		}
+
+		if s.Price.Cmp(high.Price) > 0 {
+			high = s
+		}
+
+		if s.Price.Cmp(low.Price) < 0 {
+			low = s
+		}
+	}
+
+	return ticker.Candle{
+		Ticker: tk,
+		Time:   t,
+		Open:   i.format(open.Price),
+		High:   i.format(high.Price),
+		Low:    i.format(low.Price),
+		Close:  i.format(closing.Price),
+		Count:  len(samples),
+	}
+}
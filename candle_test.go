@@ -0,0 +1,45 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexer_index_candle(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	var got []ticker.Candle
+	WithCandleHandler(func(c ticker.Candle) {
+		got = append(got, c)
+	})(env.idxer)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+		{Ticker: ticker.BTCUSDTicker, Time: now.Add(-2 * time.Second), Price: "3"},
+		{Ticker: ticker.BTCUSDTicker, Time: now.Add(-3 * time.Second), Price: "2"},
+		{Ticker: ticker.BTCUSDTicker, Time: now.Add(-time.Second), Price: "1"},
+		{Ticker: ticker.BTCUSDTicker, Time: now.Add(-2 * time.Second), Price: "5"},
+	}, nil)
+
+	require.NoError(t, env.idxer.index(ctx, now))
+
+	assert.Equal(t, []ticker.Candle{
+		{
+			Ticker: ticker.BTCUSDTicker,
+			Time:   now,
+			Open:   "2",
+			High:   "5",
+			Low:    "1",
+			Close:  "1",
+			Count:  4,
+		},
+	}, got)
+}
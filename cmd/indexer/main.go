@@ -9,23 +9,21 @@ import (
 
 	"github.com/sschiz/indexer"
 	"github.com/sschiz/indexer/collecter"
-	"github.com/sschiz/indexer/stream"
 	"github.com/sschiz/indexer/ticker"
 )
 
 func main() {
 	rand.Seed(time.Now().UnixNano())
 
-	var src source
-	priceCh, errCh := src.SubscribePriceStream(ticker.BTCUSDTicker)
-
-	s, err := stream.NewChanStream(priceCh, errCh)
+	col, err := collecter.NewSubscriptionCollecter(
+		[]ticker.PriceStreamSubscriber{&source{}},
+		[]ticker.Ticker{ticker.BTCUSDTicker},
+		collecter.WithDrain(),
+	)
 	if err != nil {
 		panic(err)
 	}
 
-	col := collecter.NewStreamCollecter([]stream.Stream{s})
-
 	idxer, err := indexer.NewIndexer(col, func(p ticker.Price) {
 		fmt.Printf("ticker = %s\ntimestamp = %d\nindex = %s\n\n\n",
 			p.Ticker, p.Time.Unix(), p.Price)
@@ -41,7 +39,7 @@ func main() {
 
 	idxer.Stop(ctx)
 
-	if err = idxer.Err(); err != nil {
+	if err = idxer.Wait(ctx); err != nil {
 		panic(err)
 	}
 }
@@ -51,22 +49,24 @@ type source struct{}
 const (
 	min = 1
 	max = 1000
+
+	bufferSize = 1024
 )
 
 func (s *source) SubscribePriceStream(t ticker.Ticker) (<-chan ticker.Price, <-chan error) {
 	errs := make(chan error)
-	prices := make(chan ticker.Price)
+	prices := make(chan ticker.Price, bufferSize)
 
 	go func() {
 		tick := time.NewTicker(time.Millisecond)
 		defer tick.Stop()
 
-		for t := range tick.C {
+		for now := range tick.C {
 			randomDecimal := min + rand.Float64()*(max-min)
 
 			prices <- ticker.Price{
-				Ticker: ticker.BTCUSDTicker,
-				Time:   t,
+				Ticker: t,
+				Time:   now,
 				Price:  strconv.FormatFloat(randomDecimal, 'f', -1, 64),
 			}
 		}
@@ -2,48 +2,380 @@ package collecter
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
 
 	"github.com/sschiz/indexer/stream"
 	"github.com/sschiz/indexer/ticker"
 	"golang.org/x/sync/errgroup"
 )
 
+var (
+	ErrInvalidStream  = errors.New("invalid stream")
+	ErrStreamNotFound = errors.New("stream not found")
+)
+
 // Collecter collects all ticker prices.
 type Collecter interface {
 	Collect(ctx context.Context) ([]*ticker.Price, error)
 }
 
+// TickerLister is implemented by collecters which know tickers of their sources.
+// Indexer drops state of tickers which are not listed anymore.
+type TickerLister interface {
+	Tickers() []ticker.Ticker
+}
+
+// StreamID identifies stream of StreamCollecter.
+type StreamID uint64
+
+type source struct {
+	id       StreamID
+	stream   stream.Stream
+	tickers  map[ticker.Ticker]struct{} // tickers the stream has produced
+	timeout  time.Duration
+	name     string
+	weight   ticker.Decimal
+	weighted bool // weight is set by StreamWeight
+}
+
+// Option configures StreamCollecter.
+type Option func(*StreamCollecter)
+
+// WithDrain makes StreamCollecter drain all prices available on streams
+// implementing stream.Drainer up to the moment Collect is called,
+// instead of getting a single price of each stream.
+func WithDrain() Option {
+	return func(c *StreamCollecter) {
+		c.drain = true
+	}
+}
+
 type StreamCollecter struct {
-	streams []stream.Stream
+	mu      sync.RWMutex
+	sources []*source
+	nextID  StreamID
+
+	drain bool
+
+	quorum         bool
+	quorumMin      int
+	quorumFraction float64
+	deadline       time.Duration
+	streamTimeout  time.Duration
+
+	handleReport func(Report)
+
+	sourceWeights map[string]ticker.Decimal
+	initial       []*source // streams added by WithStream
+	err           error     // error of invalid options
+}
+
+// WithStream adds stream configured with the given options.
+// Such streams get IDs after the streams passed to NewStreamCollecter in order of the options.
+func WithStream(s stream.Stream, opts ...StreamOption) Option {
+	return func(c *StreamCollecter) {
+		c.initial = append(c.initial, newSource(s, opts...))
+	}
 }
 
 // NewStreamCollecter returns new Collecter instance.
-func NewStreamCollecter(streams []stream.Stream) *StreamCollecter {
-	return &StreamCollecter{
-		streams: streams,
+// Streams get IDs from 0 to len(streams)-1.
+// Invalid options are reported by Err and fail each Collect.
+func NewStreamCollecter(streams []stream.Stream, opts ...Option) *StreamCollecter {
+	c := &StreamCollecter{
+		sources: make([]*source, 0, len(streams)),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.err = c.validOptions()
+
+	for _, s := range streams {
+		c.add(newSource(s))
 	}
+
+	for _, src := range c.initial {
+		if err := c.validSource(src); err != nil {
+			if c.err == nil {
+				c.err = err
+			}
+
+			continue
+		}
+
+		c.add(src)
+	}
+
+	c.initial = nil
+
+	return c
+}
+
+// Err returns error of invalid options passed to NewStreamCollecter.
+func (c *StreamCollecter) Err() error {
+	return c.err
+}
+
+// validOptions validates options passed to NewStreamCollecter.
+func (c *StreamCollecter) validOptions() error {
+	for _, w := range c.sourceWeights {
+		if !validWeight(w) {
+			return ErrInvalidWeight
+		}
+	}
+
+	return c.validQuorum()
+}
+
+// AddStream adds stream to the collecter.
+// It takes effect on the next Collect.
+func (c *StreamCollecter) AddStream(s stream.Stream, opts ...StreamOption) (StreamID, error) {
+	if s == nil {
+		return 0, ErrInvalidStream
+	}
+
+	src := newSource(s, opts...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.validSource(src); err != nil {
+		return 0, err
+	}
+
+	return c.add(src), nil
+}
+
+// RemoveStream removes stream from the collecter.
+// It takes effect on the next Collect.
+func (c *StreamCollecter) RemoveStream(id StreamID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, src := range c.sources {
+		if src.id == id {
+			c.sources = append(c.sources[:i], c.sources[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrStreamNotFound
+}
+
+// Tickers returns tickers produced by the current streams.
+func (c *StreamCollecter) Tickers() []ticker.Ticker {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	seen := make(map[ticker.Ticker]struct{})
+	tickers := make([]ticker.Ticker, 0)
+
+	for _, src := range c.sources {
+		for t := range src.tickers {
+			if _, ok := seen[t]; ok {
+				continue
+			}
+
+			seen[t] = struct{}{}
+			tickers = append(tickers, t)
+		}
+	}
+
+	return tickers
 }
 
 // Collect returns all data from streams.
 func (c *StreamCollecter) Collect(ctx context.Context) ([]*ticker.Price, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	c.mu.RLock()
+	sources := make([]*source, len(c.sources))
+	copy(sources, c.sources)
+	c.mu.RUnlock()
+
+	until := time.Now()
+	parent := ctx
+
+	if c.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.deadline)
+		defer cancel()
+	}
+
+	var (
+		prices [][]*ticker.Price
+		errs   []error
+		err    error
+	)
+
+	if c.quorum {
+		prices, errs = c.collectQuorum(ctx, sources, until)
+	} else {
+		prices, errs, err = c.collectAll(parent, ctx, sources, until)
+	}
+
+	report := Report{
+		Time:   until,
+		Errors: make(map[StreamID]error),
+	}
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		report.Errors[sources[i].id] = err
+
+		if timedOut(parent, err) {
+			report.TimedOut = append(report.TimedOut, sources[i].id)
+		}
+	}
+
+	if c.handleReport != nil {
+		c.handleReport(report)
+	}
+
+	if c.quorum {
+		required := c.required(len(sources))
+		if responded := len(sources) - len(report.Errors); responded < required {
+			return nil, &QuorumError{
+				Required:  required,
+				Responded: responded,
+				Errors:    report.Errors,
+			}
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	all := make([]*ticker.Price, 0, len(sources))
+
+	c.mu.Lock()
+	for i, src := range sources {
+		for _, price := range prices[i] {
+			src.tickers[price.Ticker] = struct{}{}
+
+			if price.Source == "" && src.name != "" {
+				p := *price
+				p.Source = src.name
+				price = &p
+			}
+
+			all = append(all, price)
+		}
+	}
+	c.mu.Unlock()
+
+	return all, nil
+}
+
+// collectAll gets prices of all sources failing on the first error except missing data.
+func (c *StreamCollecter) collectAll(
+	parent context.Context,
+	ctx context.Context,
+	sources []*source,
+	until time.Time,
+) ([][]*ticker.Price, []error, error) {
 	g, ctx := errgroup.WithContext(ctx)
-	prices := make([]*ticker.Price, len(c.streams))
-	for i, s := range c.streams {
-		i, s := i, s
+	prices := make([][]*ticker.Price, len(sources))
+	errs := make([]error, len(sources))
+	for i, src := range sources {
+		i, src := i, src
 		g.Go(func() error {
-			price, err := s.Get(ctx)
+			ps, err := c.get(ctx, src, until)
 			if err != nil {
+				errs[i] = err
+
+				if missing(parent, err) {
+					return nil
+				}
+
 				return err
 			}
 
-			prices[i] = price
+			prices[i] = ps
 			return nil
 		})
 	}
 
-	if err := g.Wait(); err != nil {
+	err := g.Wait()
+
+	return prices, errs, err
+}
+
+// missing reports whether err means the stream has no data for Collect rather than failed,
+// i.e. it timed out or has not received a price yet like stream.LatestStream.
+func missing(parent context.Context, err error) bool {
+	return timedOut(parent, err) || errors.Is(err, stream.ErrNoPrice)
+}
+
+func (c *StreamCollecter) get(ctx context.Context, src *source, until time.Time) ([]*ticker.Price, error) {
+	if timeout := c.timeout(src); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if d, ok := src.stream.(stream.Drainer); ok && c.drain {
+		return d.Drain(ctx, until)
+	}
+
+	price, err := src.stream.Get(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	return prices, nil
+	return []*ticker.Price{price}, nil
+}
+
+func (c *StreamCollecter) add(src *source) StreamID {
+	src.id = c.nextID
+	c.nextID++
+
+	c.sources = append(c.sources, src)
+
+	return src.id
+}
+
+func newSource(s stream.Stream, opts ...StreamOption) *source {
+	src := &source{
+		stream:  s,
+		tickers: make(map[ticker.Ticker]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(src)
+	}
+
+	return src
+}
+
+// validSource validates the source before it is added.
+// It must be called with c.mu held or before the collecter is returned.
+func (c *StreamCollecter) validSource(src *source) error {
+	if src.stream == nil {
+		return ErrInvalidStream
+	}
+
+	if !src.weighted {
+		return nil
+	}
+
+	if !validWeight(src.weight) || src.name == "" {
+		return ErrInvalidWeight
+	}
+
+	for _, other := range c.sources {
+		if other.name == src.name && other.weighted && other.weight.Cmp(src.weight) != 0 {
+			return ErrInvalidWeight
+		}
+	}
+
+	return nil
 }
@@ -18,9 +18,68 @@ func TestNewStreamCollecter(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
+	s1 := mock.NewMockStream(ctrl)
+	s2 := mock.NewMockStream(ctrl)
+	collecter := NewStreamCollecter([]stream.Stream{s1, s2})
+	assert.Equal(t, &StreamCollecter{
+		sources: []*source{
+			{id: 0, stream: s1, tickers: map[ticker.Ticker]struct{}{}},
+			{id: 1, stream: s2, tickers: map[ticker.Ticker]struct{}{}},
+		},
+		nextID: 2,
+	}, collecter)
+}
+
+func TestStreamCollecter_AddStream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	collecter := NewStreamCollecter(nil)
+
+	_, err := collecter.AddStream(nil)
+	require.ErrorIs(t, err, ErrInvalidStream)
+
 	s := mock.NewMockStream(ctrl)
-	collecter := NewStreamCollecter([]stream.Stream{s})
-	assert.Equal(t, &StreamCollecter{streams: []stream.Stream{s}}, collecter)
+	s.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Ticker: ticker.BTCUSDTicker}, nil)
+
+	id, err := collecter.AddStream(s)
+	require.NoError(t, err)
+	assert.Equal(t, StreamID(0), id)
+
+	prices, err := collecter.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []*ticker.Price{{Ticker: ticker.BTCUSDTicker}}, prices)
+	assert.Equal(t, []ticker.Ticker{ticker.BTCUSDTicker}, collecter.Tickers())
+}
+
+func TestStreamCollecter_RemoveStream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s1 := mock.NewMockStream(ctrl)
+	s2 := mock.NewMockStream(ctrl)
+	s3 := mock.NewMockStream(ctrl)
+
+	s1.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Ticker: ticker.BTCUSDTicker}, nil).Times(2)
+	s2.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Ticker: ethUSDTicker}, nil)
+	s3.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Ticker: ticker.BTCUSDTicker}, nil)
+
+	collecter := NewStreamCollecter([]stream.Stream{s1, s2, s3})
+
+	_, err := collecter.Collect(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []ticker.Ticker{ticker.BTCUSDTicker, ethUSDTicker}, collecter.Tickers())
+
+	require.ErrorIs(t, collecter.RemoveStream(42), ErrStreamNotFound)
+	require.NoError(t, collecter.RemoveStream(1))
+	require.NoError(t, collecter.RemoveStream(2))
+	require.ErrorIs(t, collecter.RemoveStream(2), ErrStreamNotFound)
+
+	assert.Equal(t, []ticker.Ticker{ticker.BTCUSDTicker}, collecter.Tickers())
+
+	prices, err := collecter.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []*ticker.Price{{Ticker: ticker.BTCUSDTicker}}, prices)
 }
 
 func TestStreamCollecter_Collect(t *testing.T) {
@@ -83,4 +142,61 @@ func TestStreamCollecter_Collect(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, expected, prices)
 	})
+
+	t.Run("latest stream without price", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.Background()
+		healthy := &ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "1"}
+
+		s1 := mock.NewMockStream(ctrl)
+		s1.EXPECT().Get(gomock.Any()).Return(healthy, nil)
+
+		silent, err := stream.NewChanStream(make(chan ticker.Price), make(chan error))
+		require.NoError(t, err)
+
+		s2, err := stream.NewLatestStream(ctx, silent)
+		require.NoError(t, err)
+		defer s2.Close()
+
+		var report Report
+		collecter := NewStreamCollecter(
+			[]stream.Stream{s1, s2},
+			WithReportHandler(func(r Report) { report = r }),
+		)
+
+		prices, err := collecter.Collect(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*ticker.Price{healthy}, prices)
+		assert.ErrorIs(t, report.Errors[1], stream.ErrNoPrice)
+		assert.Empty(t, report.TimedOut)
+	})
+}
+
+func TestStreamCollecter_Collect_drain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tick := make(chan ticker.Price, 3)
+	for _, p := range []string{"1", "2", "3"} {
+		tick <- ticker.Price{Ticker: ticker.BTCUSDTicker, Price: p}
+	}
+
+	s1, err := stream.NewChanStream(tick, make(chan error))
+	require.NoError(t, err)
+
+	s2 := mock.NewMockStream(ctrl)
+	s2.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Ticker: ethUSDTicker, Price: "4"}, nil)
+
+	collecter := NewStreamCollecter([]stream.Stream{s1, s2}, WithDrain())
+
+	prices, err := collecter.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []*ticker.Price{
+		{Ticker: ticker.BTCUSDTicker, Price: "1"},
+		{Ticker: ticker.BTCUSDTicker, Price: "2"},
+		{Ticker: ticker.BTCUSDTicker, Price: "3"},
+		{Ticker: ethUSDTicker, Price: "4"},
+	}, prices)
 }
@@ -0,0 +1,139 @@
+package collecter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sschiz/indexer/ticker"
+)
+
+var (
+	ErrQuorumNotReached = errors.New("quorum not reached")
+	ErrInvalidQuorum    = errors.New("invalid quorum")
+	ErrInvalidDeadline  = errors.New("invalid deadline")
+)
+
+// DefaultQuorumDeadline limits duration of Collect in quorum mode if WithDeadline is not set,
+// so a hung stream does not block it forever.
+const DefaultQuorumDeadline = 10 * time.Second
+
+// Report describes a single Collect call.
+type Report struct {
+	Time     time.Time
+	Errors   map[StreamID]error // errors of streams which did not respond
+	TimedOut []StreamID         // streams which did not respond in time
+}
+
+// QuorumError is returned by Collect when too few streams responded.
+type QuorumError struct {
+	Required  int
+	Responded int
+	Errors    map[StreamID]error
+}
+
+func (e *QuorumError) Error() string {
+	return fmt.Sprintf("%v: %d of %d required streams responded", ErrQuorumNotReached, e.Responded, e.Required)
+}
+
+// Is reports whether target is ErrQuorumNotReached.
+func (e *QuorumError) Is(target error) bool {
+	return target == ErrQuorumNotReached
+}
+
+// WithQuorum makes StreamCollecter tolerate failing streams.
+// Collect returns prices of streams which responded and fails with QuorumError
+// only if less than min streams responded. Min must not be negative.
+// DefaultQuorumDeadline is used unless WithDeadline is set.
+func WithQuorum(min int) Option {
+	return func(c *StreamCollecter) {
+		c.quorum = true
+		c.quorumMin = min
+	}
+}
+
+// WithQuorumFraction is like WithQuorum, but the minimum is a fraction of all streams.
+// It can be combined with WithQuorum, then the larger minimum is required.
+// Fraction must be in [0, 1].
+func WithQuorumFraction(fraction float64) Option {
+	return func(c *StreamCollecter) {
+		c.quorum = true
+		c.quorumFraction = fraction
+	}
+}
+
+// WithDeadline limits duration of Collect.
+// Streams which did not respond in time are treated as missing data like with WithStreamTimeout.
+// Zero means no deadline except DefaultQuorumDeadline in quorum mode.
+func WithDeadline(d time.Duration) Option {
+	return func(c *StreamCollecter) {
+		c.deadline = d
+	}
+}
+
+// WithReportHandler sets handler which is called with Report after each Collect.
+func WithReportHandler(handle func(Report)) Option {
+	return func(c *StreamCollecter) {
+		c.handleReport = handle
+	}
+}
+
+// validQuorum validates quorum options and sets the default deadline of quorum mode.
+func (c *StreamCollecter) validQuorum() error {
+	if c.deadline < 0 {
+		return ErrInvalidDeadline
+	}
+
+	if !c.quorum {
+		return nil
+	}
+
+	if c.quorumMin < 0 || !(c.quorumFraction >= 0 && c.quorumFraction <= 1) {
+		return ErrInvalidQuorum
+	}
+
+	if c.deadline == 0 {
+		c.deadline = DefaultQuorumDeadline
+	}
+
+	return nil
+}
+
+// required returns minimum number of streams which must respond.
+func (c *StreamCollecter) required(streams int) int {
+	required := int(math.Ceil(c.quorumFraction * float64(streams)))
+	if c.quorumMin > required {
+		required = c.quorumMin
+	}
+
+	return required
+}
+
+// collectQuorum gets prices of all sources waiting for each of them.
+func (c *StreamCollecter) collectQuorum(
+	ctx context.Context,
+	sources []*source,
+	until time.Time,
+) ([][]*ticker.Price, []error) {
+	var wg sync.WaitGroup
+
+	prices := make([][]*ticker.Price, len(sources))
+	errs := make([]error, len(sources))
+
+	for i, src := range sources {
+		wg.Add(1)
+
+		go func(i int, src *source) {
+			defer wg.Done()
+
+			prices[i], errs[i] = c.get(ctx, src, until)
+		}(i, src)
+	}
+
+	wg.Wait()
+
+	return prices, errs
+}
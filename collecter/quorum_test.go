@@ -0,0 +1,138 @@
+package collecter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/mock"
+	"github.com/sschiz/indexer/stream"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamCollecter_Collect_quorum(t *testing.T) {
+	expectedErr := errors.New("stream error")
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		wantErr  bool
+		required int
+	}{
+		{
+			name: "min reached",
+			opts: []Option{WithQuorum(1)},
+		},
+		{
+			name: "fraction reached",
+			opts: []Option{WithQuorumFraction(0.5)},
+		},
+		{
+			name:     "min not reached",
+			opts:     []Option{WithQuorum(3)},
+			wantErr:  true,
+			required: 3,
+		},
+		{
+			name:     "fraction not reached",
+			opts:     []Option{WithQuorumFraction(0.6)},
+			wantErr:  true,
+			required: 3,
+		},
+		{
+			name:     "larger minimum",
+			opts:     []Option{WithQuorum(3), WithQuorumFraction(0.1)},
+			wantErr:  true,
+			required: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			s1 := mock.NewMockStream(ctrl)
+			s2 := mock.NewMockStream(ctrl)
+			s3 := mock.NewMockStream(ctrl)
+			s4 := mock.NewMockStream(ctrl)
+
+			s1.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Price: "1"}, nil)
+			s2.EXPECT().Get(gomock.Any()).Return(nil, expectedErr)
+			s3.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Price: "3"}, nil)
+			s4.EXPECT().Get(gomock.Any()).DoAndReturn(func(ctx context.Context) (*ticker.Price, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+
+			var reports []Report
+			opts := append([]Option{
+				WithDeadline(10 * time.Millisecond),
+				WithReportHandler(func(r Report) { reports = append(reports, r) }),
+			}, tt.opts...)
+
+			collecter := NewStreamCollecter([]stream.Stream{s1, s2, s3, s4}, opts...)
+
+			prices, err := collecter.Collect(context.Background())
+
+			require.Len(t, reports, 1)
+			assert.Len(t, reports[0].Errors, 2)
+			assert.ErrorIs(t, reports[0].Errors[1], expectedErr)
+			assert.ErrorIs(t, reports[0].Errors[3], context.DeadlineExceeded)
+			assert.Equal(t, []StreamID{3}, reports[0].TimedOut)
+
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Equal(t, []*ticker.Price{{Price: "1"}, {Price: "3"}}, prices)
+
+				return
+			}
+
+			require.Nil(t, prices)
+			require.ErrorIs(t, err, ErrQuorumNotReached)
+
+			var quorumErr *QuorumError
+			require.ErrorAs(t, err, &quorumErr)
+			assert.Equal(t, tt.required, quorumErr.Required)
+			assert.Equal(t, 2, quorumErr.Responded)
+			assert.Equal(t, reports[0].Errors, quorumErr.Errors)
+		})
+	}
+}
+
+func TestNewStreamCollecter_quorum(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		for _, opts := range [][]Option{
+			{WithQuorum(-1)},
+			{WithQuorumFraction(-0.1)},
+			{WithQuorumFraction(1.1)},
+			{WithQuorumFraction(math.NaN())},
+		} {
+			collecter := NewStreamCollecter(nil, opts...)
+			assert.ErrorIs(t, collecter.Err(), ErrInvalidQuorum)
+
+			prices, err := collecter.Collect(context.Background())
+			require.Nil(t, prices)
+			assert.ErrorIs(t, err, ErrInvalidQuorum)
+		}
+
+		collecter := NewStreamCollecter(nil, WithDeadline(-time.Second))
+		assert.ErrorIs(t, collecter.Err(), ErrInvalidDeadline)
+	})
+
+	t.Run("default deadline", func(t *testing.T) {
+		collecter := NewStreamCollecter(nil, WithQuorum(1))
+		require.NoError(t, collecter.Err())
+		assert.Equal(t, DefaultQuorumDeadline, collecter.deadline)
+
+		collecter = NewStreamCollecter(nil, WithQuorumFraction(1), WithDeadline(time.Second))
+		assert.Equal(t, time.Second, collecter.deadline)
+
+		collecter = NewStreamCollecter(nil)
+		assert.Zero(t, collecter.deadline)
+	})
+}
@@ -0,0 +1,60 @@
+package collecter
+
+import (
+	"errors"
+
+	"github.com/sschiz/indexer/stream"
+	"github.com/sschiz/indexer/ticker"
+)
+
+var ErrInvalidSubscriber = errors.New("invalid subscriber")
+
+// SourceNamer is implemented by subscribers which name the source of their prices,
+// e.g. an exchange.
+type SourceNamer interface {
+	Source() string
+}
+
+// NewSubscriptionCollecter subscribes each subscriber to each ticker
+// and returns StreamCollecter of all the subscriptions.
+// Nothing is subscribed if any subscriber is nil.
+// Streams of subscribers implementing SourceNamer get their StreamSource,
+// so they can be weighted by WithSourceWeight.
+func NewSubscriptionCollecter(
+	subs []ticker.PriceStreamSubscriber,
+	tickers []ticker.Ticker,
+	opts ...Option,
+) (*StreamCollecter, error) {
+	for _, sub := range subs {
+		if sub == nil {
+			return nil, ErrInvalidSubscriber
+		}
+	}
+
+	c := NewStreamCollecter(nil, opts...)
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, sub := range subs {
+		var name string
+		if n, ok := sub.(SourceNamer); ok {
+			name = n.Source()
+		}
+
+		for _, t := range tickers {
+			prices, errs := sub.SubscribePriceStream(t)
+
+			s, err := stream.NewChanStream(prices, errs)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, err := c.AddStream(s, StreamSource(name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return c, nil
+}
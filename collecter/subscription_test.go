@@ -0,0 +1,102 @@
+package collecter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sschiz/indexer/stream"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ethUSDTicker ticker.Ticker = "ETH_USD"
+
+type subscriber struct {
+	subscribed []ticker.Ticker
+	nilChans   bool
+}
+
+func (s *subscriber) SubscribePriceStream(t ticker.Ticker) (<-chan ticker.Price, <-chan error) {
+	s.subscribed = append(s.subscribed, t)
+
+	if s.nilChans {
+		return nil, nil
+	}
+
+	prices := make(chan ticker.Price, 1)
+	prices <- ticker.Price{Ticker: t, Price: "1"}
+
+	return prices, make(chan error)
+}
+
+type namedSubscriber struct {
+	subscriber
+	name string
+}
+
+func (s *namedSubscriber) Source() string {
+	return s.name
+}
+
+func TestNewSubscriptionCollecter(t *testing.T) {
+	t.Run("invalid subscriber", func(t *testing.T) {
+		sub := &subscriber{}
+
+		got, err := NewSubscriptionCollecter(
+			[]ticker.PriceStreamSubscriber{sub, nil},
+			[]ticker.Ticker{ticker.BTCUSDTicker},
+		)
+
+		require.Nil(t, got)
+		assert.ErrorIs(t, err, ErrInvalidSubscriber)
+		assert.Empty(t, sub.subscribed)
+	})
+
+	t.Run("invalid channel", func(t *testing.T) {
+		got, err := NewSubscriptionCollecter(
+			[]ticker.PriceStreamSubscriber{&subscriber{nilChans: true}},
+			[]ticker.Ticker{ticker.BTCUSDTicker},
+		)
+
+		require.Nil(t, got)
+		assert.ErrorIs(t, err, stream.ErrInvalidChannel)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		s1, s2 := &subscriber{}, &subscriber{}
+		tickers := []ticker.Ticker{ticker.BTCUSDTicker, ethUSDTicker}
+
+		got, err := NewSubscriptionCollecter([]ticker.PriceStreamSubscriber{s1, s2}, tickers)
+		require.NoError(t, err)
+
+		assert.Equal(t, tickers, s1.subscribed)
+		assert.Equal(t, tickers, s2.subscribed)
+
+		prices, err := got.Collect(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Price: "1"},
+			{Ticker: ethUSDTicker, Price: "1"},
+			{Ticker: ticker.BTCUSDTicker, Price: "1"},
+			{Ticker: ethUSDTicker, Price: "1"},
+		}, prices)
+	})
+
+	t.Run("source", func(t *testing.T) {
+		s1, s2 := &namedSubscriber{name: "binance"}, &subscriber{}
+
+		got, err := NewSubscriptionCollecter(
+			[]ticker.PriceStreamSubscriber{s1, s2},
+			[]ticker.Ticker{ticker.BTCUSDTicker},
+		)
+		require.NoError(t, err)
+
+		prices, err := got.Collect(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Price: "1", Source: "binance"},
+			{Ticker: ticker.BTCUSDTicker, Price: "1"},
+		}, prices)
+	})
+}
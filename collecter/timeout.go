@@ -0,0 +1,42 @@
+package collecter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// StreamOption configures a stream of StreamCollecter.
+type StreamOption func(*source)
+
+// StreamTimeout limits duration of getting prices of the stream.
+// It overrides timeout set by WithStreamTimeout.
+func StreamTimeout(d time.Duration) StreamOption {
+	return func(s *source) {
+		s.timeout = d
+	}
+}
+
+// WithStreamTimeout limits duration of getting prices of each stream.
+// Streams which did not respond in time are treated as missing data,
+// they are listed in Report.TimedOut and do not fail Collect on their own.
+func WithStreamTimeout(d time.Duration) Option {
+	return func(c *StreamCollecter) {
+		c.streamTimeout = d
+	}
+}
+
+// timeout returns timeout of the source.
+func (c *StreamCollecter) timeout(src *source) time.Duration {
+	if src.timeout > 0 {
+		return src.timeout
+	}
+
+	return c.streamTimeout
+}
+
+// timedOut reports whether err is caused by the collect deadline or the stream timeout
+// rather than by the caller.
+func timedOut(parent context.Context, err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) && parent.Err() == nil
+}
@@ -0,0 +1,115 @@
+package collecter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/mock"
+	"github.com/sschiz/indexer/stream"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hang(ctx context.Context) (*ticker.Price, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestStreamCollecter_Collect_timeout(t *testing.T) {
+	t.Run("stream timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		s1 := mock.NewMockStream(ctrl)
+		s2 := mock.NewMockStream(ctrl)
+
+		s1.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Price: "1"}, nil)
+		s2.EXPECT().Get(gomock.Any()).DoAndReturn(hang)
+
+		var reports []Report
+		collecter := NewStreamCollecter(
+			[]stream.Stream{s1, s2},
+			WithStreamTimeout(10*time.Millisecond),
+			WithReportHandler(func(r Report) { reports = append(reports, r) }),
+		)
+
+		prices, err := collecter.Collect(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []*ticker.Price{{Price: "1"}}, prices)
+
+		require.Len(t, reports, 1)
+		assert.Equal(t, []StreamID{1}, reports[0].TimedOut)
+		assert.ErrorIs(t, reports[0].Errors[1], context.DeadlineExceeded)
+	})
+
+	t.Run("per-stream timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		s1 := mock.NewMockStream(ctrl)
+		s2 := mock.NewMockStream(ctrl)
+
+		s1.EXPECT().Get(gomock.Any()).DoAndReturn(hang)
+		s2.EXPECT().Get(gomock.Any()).DoAndReturn(func(ctx context.Context) (*ticker.Price, error) {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				return &ticker.Price{Price: "2"}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		})
+
+		var reports []Report
+		collecter := NewStreamCollecter(
+			nil,
+			WithStreamTimeout(time.Second),
+			WithReportHandler(func(r Report) { reports = append(reports, r) }),
+		)
+
+		_, err := collecter.AddStream(s1, StreamTimeout(10*time.Millisecond))
+		require.NoError(t, err)
+		_, err = collecter.AddStream(s2)
+		require.NoError(t, err)
+
+		prices, err := collecter.Collect(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []*ticker.Price{{Price: "2"}}, prices)
+
+		require.Len(t, reports, 1)
+		assert.Equal(t, []StreamID{0}, reports[0].TimedOut)
+	})
+
+	t.Run("collect deadline", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		s := mock.NewMockStream(ctrl)
+		s.EXPECT().Get(gomock.Any()).DoAndReturn(hang)
+
+		collecter := NewStreamCollecter([]stream.Stream{s}, WithDeadline(10*time.Millisecond))
+
+		prices, err := collecter.Collect(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, prices)
+	})
+
+	t.Run("caller deadline", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		s := mock.NewMockStream(ctrl)
+		s.EXPECT().Get(gomock.Any()).DoAndReturn(hang)
+
+		collecter := NewStreamCollecter([]stream.Stream{s}, WithStreamTimeout(time.Second))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		prices, err := collecter.Collect(ctx)
+		require.Nil(t, prices)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
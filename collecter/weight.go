@@ -0,0 +1,66 @@
+package collecter
+
+import (
+	"errors"
+
+	"github.com/sschiz/indexer/ticker"
+)
+
+var ErrInvalidWeight = errors.New("invalid weight")
+
+// SourceWeigher is implemented by collecters which know weights of sources of their prices.
+// Indexer weights each price by its Source.
+type SourceWeigher interface {
+	// SourceWeight returns weight of the source, zero if it has no weight.
+	SourceWeight(source string) ticker.Decimal
+}
+
+// StreamSource sets identifier of the stream source, e.g. name of the exchange.
+// It is set to Source of prices the stream produces without one.
+func StreamSource(name string) StreamOption {
+	return func(s *source) {
+		s.name = name
+	}
+}
+
+// StreamWeight sets weight of the stream source in the index calculation.
+// Weight must be positive and the stream must have StreamSource, since prices are weighted by their Source.
+// It overrides weight set by WithSourceWeight, streams of the same source must have the same weight.
+func StreamWeight(w ticker.Decimal) StreamOption {
+	return func(s *source) {
+		s.weight = w
+		s.weighted = true
+	}
+}
+
+// WithSourceWeight sets weight of streams with the given StreamSource,
+// including ones added later. Weight must be positive.
+// Streams without weight have weight of one.
+func WithSourceWeight(name string, w ticker.Decimal) Option {
+	return func(c *StreamCollecter) {
+		if c.sourceWeights == nil {
+			c.sourceWeights = make(map[string]ticker.Decimal)
+		}
+
+		c.sourceWeights[name] = w
+	}
+}
+
+// SourceWeight returns weight of the source set by StreamWeight or WithSourceWeight,
+// zero if it has no weight.
+func (c *StreamCollecter) SourceWeight(name string) ticker.Decimal {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, src := range c.sources {
+		if src.name == name && src.weighted {
+			return src.weight
+		}
+	}
+
+	return c.sourceWeights[name]
+}
+
+func validWeight(w ticker.Decimal) bool {
+	return w.Sign() > 0
+}
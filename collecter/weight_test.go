@@ -0,0 +1,100 @@
+package collecter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/mock"
+	"github.com/sschiz/indexer/stream"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamCollecter_weight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	w := func(s string) ticker.Decimal {
+		d, err := ticker.ParseDecimal(s)
+		require.NoError(t, err)
+
+		return d
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, weight := range []string{"0", "-1"} {
+			collecter := NewStreamCollecter(nil, WithSourceWeight("binance", w(weight)))
+			require.ErrorIs(t, collecter.Err(), ErrInvalidWeight, weight)
+
+			collecter = NewStreamCollecter(nil,
+				WithStream(mock.NewMockStream(ctrl), StreamSource("binance"), StreamWeight(w(weight))))
+			require.ErrorIs(t, collecter.Err(), ErrInvalidWeight, weight)
+
+			collecter = NewStreamCollecter(nil)
+			require.NoError(t, collecter.Err())
+
+			_, err := collecter.AddStream(mock.NewMockStream(ctrl), StreamSource("binance"), StreamWeight(w(weight)))
+			require.ErrorIs(t, err, ErrInvalidWeight, weight)
+		}
+
+		collecter := NewStreamCollecter(nil)
+
+		// Prices of unnamed stream can not be weighted by source.
+		_, err := collecter.AddStream(mock.NewMockStream(ctrl), StreamWeight(w("2")))
+		require.ErrorIs(t, err, ErrInvalidWeight)
+
+		_, err = collecter.AddStream(mock.NewMockStream(ctrl), StreamSource("kraken"), StreamWeight(w("2")))
+		require.NoError(t, err)
+
+		_, err = collecter.AddStream(mock.NewMockStream(ctrl), StreamSource("kraken"), StreamWeight(w("2")))
+		require.NoError(t, err)
+
+		// Conflicting weight of the same source.
+		_, err = collecter.AddStream(mock.NewMockStream(ctrl), StreamSource("kraken"), StreamWeight(w("3")))
+		require.ErrorIs(t, err, ErrInvalidWeight)
+	})
+
+	t.Run("source weight", func(t *testing.T) {
+		s1 := mock.NewMockStream(ctrl)
+		s1.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "1"}, nil)
+
+		s2 := mock.NewMockStream(ctrl)
+		s2.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "2", Source: "feed"}, nil)
+
+		s3 := mock.NewMockStream(ctrl)
+		s3.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "3"}, nil)
+
+		collecter := NewStreamCollecter(
+			[]stream.Stream{s1},
+			WithSourceWeight("kraken", w("0.3")),
+			WithSourceWeight("bitstamp", w("0.2")),
+			WithStream(s2, StreamSource("kraken")),
+			WithStream(s3, StreamSource("binance"), StreamWeight(w("0.1"))),
+		)
+		require.NoError(t, collecter.Err())
+
+		id, err := collecter.AddStream(mock.NewMockStream(ctrl), StreamSource("bitstamp"), StreamWeight(w("2")))
+		require.NoError(t, err)
+		assert.Equal(t, StreamID(3), id)
+
+		assert.Equal(t, w("0.3"), collecter.SourceWeight("kraken"))
+		assert.Equal(t, w("0.1"), collecter.SourceWeight("binance"))
+		assert.Equal(t, w("2"), collecter.SourceWeight("bitstamp"))
+		assert.True(t, collecter.SourceWeight("feed").IsZero())
+
+		require.NoError(t, collecter.RemoveStream(id))
+		assert.Equal(t, w("0.2"), collecter.SourceWeight("bitstamp"))
+
+		prices, err := collecter.Collect(context.Background())
+		require.NoError(t, err)
+		require.Len(t, prices, 3)
+
+		sources := map[string]string{}
+		for _, price := range prices {
+			sources[price.Price] = price.Source
+		}
+		assert.Equal(t, map[string]string{"1": "", "2": "feed", "3": "binance"}, sources)
+	})
+}
@@ -0,0 +1,128 @@
+package indexer
+
+import (
+	"time"
+
+	"github.com/sschiz/indexer/ticker"
+)
+
+// Constituent describes a price collected at the tick for the published index.
+type Constituent struct {
+	Source   string
+	Price    string // price as collected
+	Time     time.Time
+	Excluded bool
+	Reason   error          // reason of exclusion: ErrStalePrice, ErrOutlier, ErrQuarantined or *PriceError
+	Weight   ticker.Decimal // share of the price in the index, zero if excluded or the aggregator is not a Weigher
+
+	sample Sample // included sample
+}
+
+// DetailedHandler is called for each published index with prices collected for it at the tick.
+type DetailedHandler func(ticker.Price, []Constituent)
+
+// WithDetailedHandler sets handler which receives each published index
+// along with its constituents: included and excluded prices collected at the tick.
+// Weights of included prices are relative to all prices the index covers according to the window.
+// It is called after Handler. Tickers quarantined at the tick are not published,
+// the handler receives them with empty Price and all their prices excluded.
+func WithDetailedHandler(handle DetailedHandler) Option {
+	return func(i *Indexer) {
+		i.handleDetailed = handle
+	}
+}
+
+// include records constituents of samples included at the tick.
+func (i *Indexer) include(samples map[ticker.Ticker][]Sample) {
+	if i.handleDetailed == nil {
+		return
+	}
+
+	for tk, ss := range samples {
+		for _, s := range ss {
+			c := constituent(s, nil)
+			c.sample = s
+
+			i.constituents[tk] = append(i.constituents[tk], c)
+		}
+	}
+}
+
+// excludeQuarantined records all prices of quarantined tickers as excluded constituents.
+// Invalid prices are excluded with their PriceError, valid ones with ErrQuarantined.
+func (i *Indexer) excludeQuarantined(
+	prices []*ticker.Price,
+	quarantined map[ticker.Ticker]struct{},
+	rejected map[*ticker.Price]*PriceError,
+) {
+	for _, price := range prices {
+		if _, ok := quarantined[price.Ticker]; !ok {
+			continue
+		}
+
+		var reason error = ErrQuarantined
+		if err, ok := rejected[price]; ok {
+			reason = err
+		}
+
+		i.constituents[price.Ticker] = append(i.constituents[price.Ticker], Constituent{
+			Source:   price.Source,
+			Price:    price.Price,
+			Time:     price.Time,
+			Excluded: true,
+			Reason:   reason,
+		})
+	}
+}
+
+// publish calls handlers of the index.
+func (i *Indexer) publish(price ticker.Price) {
+	i.handle(price)
+
+	if i.handleDetailed != nil {
+		i.handleDetailed(price, i.weigh(price.Ticker, price.Time))
+	}
+}
+
+// weigh sets weights of constituents included at tick t
+// relative to the total weight of the aggregator of the ticker.
+func (i *Indexer) weigh(tk ticker.Ticker, t time.Time) []Constituent {
+	cs := i.constituents[tk]
+
+	w, ok := i.aggs[tk].(Weigher)
+	if !ok {
+		return cs
+	}
+
+	total := w.TotalWeight()
+	if total.IsZero() {
+		return cs
+	}
+
+	cutoff := t.Add(-i.lookback)
+
+	for j, c := range cs {
+		if c.Excluded {
+			continue
+		}
+
+		// sample may be evicted from the sliding window at once.
+		if i.window == SlidingWindow && !c.Time.IsZero() && !c.Time.After(cutoff) {
+			continue
+		}
+
+		cs[j].Weight = w.Weigh(c.sample).Quo(total)
+	}
+
+	return cs
+}
+
+func constituent(s Sample, reason error) Constituent {
+	return Constituent{
+		Source:   s.Source,
+		Price:    s.raw,
+		Time:     s.Time,
+		Excluded: reason != nil,
+		Reason:   reason,
+	}
+}
@@ -0,0 +1,195 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexer_index_constituents(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	filter, err := NewPercentFilter(5)
+	require.NoError(t, err)
+
+	WithOutlierFilter(filter)(env.idxer)
+	WithMaxAge(time.Minute)(env.idxer)
+	sourceWeights(t, env, map[string]string{"binance": "3"})
+
+	type report struct {
+		price        ticker.Price
+		constituents []Constituent
+	}
+
+	var got []report
+	WithDetailedHandler(func(tp ticker.Price, cs []Constituent) {
+		got = append(got, report{price: tp, constituents: cs})
+	})(env.idxer)
+
+	ctx := context.Background()
+	now := time.Now()
+	old := now.Add(-time.Hour)
+
+	env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "100.0", Source: "binance"},
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "104", Source: "kraken"},
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "101", Source: "coinbase"},
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "150", Source: "bitstamp"},
+		{Ticker: ticker.BTCUSDTicker, Time: old, Price: "90", Source: "huobi"},
+	}, nil)
+
+	require.NoError(t, env.idxer.index(ctx, now))
+	require.Len(t, got, 1)
+
+	assert.Equal(t, ticker.Price{Ticker: ticker.BTCUSDTicker, Time: now, Price: "101"}, got[0].price)
+
+	cs := got[0].constituents
+	require.Len(t, cs, 5)
+
+	assert.Equal(t, "huobi", cs[0].Source)
+	assert.Equal(t, "90", cs[0].Price)
+	assert.Equal(t, old, cs[0].Time)
+	assert.True(t, cs[0].Excluded)
+	assert.ErrorIs(t, cs[0].Reason, ErrStalePrice)
+	assert.True(t, cs[0].Weight.IsZero())
+
+	assert.Equal(t, "bitstamp", cs[1].Source)
+	assert.True(t, cs[1].Excluded)
+	assert.ErrorIs(t, cs[1].Reason, ErrOutlier)
+
+	for j, want := range []struct {
+		source string
+		price  string
+		weight float64
+	}{
+		{source: "binance", price: "100.0", weight: 0.6},
+		{source: "kraken", price: "104", weight: 0.2},
+		{source: "coinbase", price: "101", weight: 0.2},
+	} {
+		c := cs[2+j]
+		assert.Equal(t, want.source, c.Source)
+		assert.Equal(t, want.price, c.Price)
+		assert.False(t, c.Excluded)
+		assert.NoError(t, c.Reason)
+		assert.Equal(t, want.weight, c.Weight.Float64())
+	}
+}
+
+func TestIndexer_index_constituents_weight(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []string // weights of constituents at the second tick
+	}{
+		{
+			name: "cumulative",
+			want: []string{"0.375", "0.125"},
+		},
+		{
+			name: "tumbling",
+			opts: []Option{WithWindow(TumblingWindow)},
+			want: []string{"0.75", "0.25"},
+		},
+		{
+			name: "sliding",
+			opts: []Option{WithSlidingWindow(time.Minute)},
+			want: []string{"0.6", "0.2"},
+		},
+		{
+			name: "vwap",
+			opts: []Option{WithWindow(TumblingWindow), WithAggregator(func() Aggregator { return NewVWAP() })},
+			want: []string{"0.9", "0.1"},
+		},
+		{
+			name: "median",
+			opts: []Option{WithAggregator(func() Aggregator { return NewMedian() })},
+			want: []string{"0", "0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tearUp(t)
+			defer tearDown(env)
+
+			for _, opt := range tt.opts {
+				opt(env.idxer)
+			}
+			sourceWeights(t, env, map[string]string{"binance": "3", "kraken": "3"})
+
+			var got [][]Constituent
+			WithDetailedHandler(func(_ ticker.Price, cs []Constituent) {
+				got = append(got, cs)
+			})(env.idxer)
+
+			ctx := context.Background()
+			now := time.Now()
+
+			gomock.InOrder(
+				env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+					{Ticker: ticker.BTCUSDTicker, Time: now.Add(-2 * time.Minute), Price: "10", Source: "binance"},
+					{Ticker: ticker.BTCUSDTicker, Time: now, Price: "20", Source: "coinbase"},
+				}, nil),
+				env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+					{Ticker: ticker.BTCUSDTicker, Time: now, Price: "20", Volume: "3", Source: "kraken"},
+					{Ticker: ticker.BTCUSDTicker, Time: now, Price: "40", Source: "bitstamp"},
+				}, nil),
+			)
+
+			require.NoError(t, env.idxer.index(ctx, now))
+			require.NoError(t, env.idxer.index(ctx, now))
+
+			require.Len(t, got, 2)
+			require.Len(t, got[1], len(tt.want))
+
+			for j, want := range tt.want {
+				assert.Equal(t, weight(t, want).String(), got[1][j].Weight.String())
+			}
+		})
+	}
+}
+
+func TestIndexer_index_constituents_quarantine(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	WithQuarantine(nil)(env.idxer)
+
+	var (
+		published []ticker.Price
+		got       []Constituent
+	)
+	WithDetailedHandler(func(tp ticker.Price, cs []Constituent) {
+		published = append(published, tp)
+		got = cs
+	})(env.idxer)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "10", Source: "binance"},
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "-1", Source: "kraken"},
+	}, nil)
+
+	require.NoError(t, env.idxer.index(ctx, now))
+
+	assert.Equal(t, []ticker.Price{{Ticker: ticker.BTCUSDTicker, Time: now}}, published)
+	require.Len(t, got, 2)
+
+	assert.Equal(t, "binance", got[0].Source)
+	assert.True(t, got[0].Excluded)
+	assert.ErrorIs(t, got[0].Reason, ErrQuarantined)
+
+	var priceErr *PriceError
+	assert.Equal(t, "kraken", got[1].Source)
+	assert.Equal(t, "-1", got[1].Price)
+	assert.True(t, got[1].Excluded)
+	require.ErrorAs(t, got[1].Reason, &priceErr)
+	assert.ErrorIs(t, priceErr, ErrNonPositivePrice)
+}
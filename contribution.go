@@ -0,0 +1,98 @@
+package indexer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sschiz/indexer/ticker"
+)
+
+// Contribution describes prices of a single source the published index covers.
+type Contribution struct {
+	Source string         // empty for prices of unknown source
+	Count  int            // number of prices
+	Weight ticker.Decimal // weight of the prices in the index, zero if the aggregator is not a Weigher
+	Share  ticker.Decimal // Weight relative to the total weight of all sources
+}
+
+// Contributions describes sources of the published index.
+type Contributions struct {
+	Ticker  ticker.Ticker
+	Time    time.Time
+	Price   string
+	Stale   bool
+	Sources []Contribution // sorted by source
+}
+
+// ContributionHandler is called for each published index.
+type ContributionHandler func(Contributions)
+
+// WithContributionHandler sets handler of contributions of sources to the published index.
+// Contributions cover the same prices the index does according to the window.
+// Weights are reported by aggregators implementing Weigher only, e.g. volume-weighted ones by VWAP,
+// since weights of others such as Median or TWAP depend on the value itself.
+func WithContributionHandler(handle ContributionHandler) Option {
+	return func(i *Indexer) {
+		i.handleContribution = handle
+	}
+}
+
+// add adds sample to the aggregator of the ticker.
+func (i *Indexer) add(tk ticker.Ticker, s Sample) {
+	agg := i.aggregator(tk)
+	agg.Add(s)
+
+	if i.handleContribution == nil {
+		return
+	}
+
+	contribs, ok := i.contribs[tk]
+	if !ok {
+		contribs = make(map[string]*Contribution)
+		i.contribs[tk] = contribs
+	}
+
+	c, ok := contribs[s.Source]
+	if !ok {
+		c = &Contribution{Source: s.Source}
+		contribs[s.Source] = c
+	}
+
+	c.Count++
+
+	if w, ok := agg.(Weigher); ok {
+		c.Weight = c.Weight.Add(w.Weigh(s))
+	}
+}
+
+// contributions returns contributions to the index of the ticker.
+func (i *Indexer) contributions(tk ticker.Ticker, t time.Time, price string, stale bool) Contributions {
+	contribs := i.contribs[tk]
+
+	var total ticker.Decimal
+	for _, c := range contribs {
+		total = total.Add(c.Weight)
+	}
+
+	sources := make([]Contribution, 0, len(contribs))
+	for _, c := range contribs {
+		share := *c
+		if !total.IsZero() {
+			share.Share = c.Weight.Quo(total)
+		}
+
+		sources = append(sources, share)
+	}
+
+	sort.Slice(sources, func(a, b int) bool {
+		return sources[a].Source < sources[b].Source
+	})
+
+	return Contributions{
+		Ticker:  tk,
+		Time:    t,
+		Price:   price,
+		Stale:   stale,
+		Sources: sources,
+	}
+}
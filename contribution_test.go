@@ -0,0 +1,155 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexer_index_contributions(t *testing.T) {
+	tests := []struct {
+		name   string
+		window Window
+		want   [][]Contribution
+	}{
+		{
+			name:   "cumulative",
+			window: CumulativeWindow,
+			want: [][]Contribution{
+				{
+					{Source: "binance", Count: 1, Weight: dec(3), Share: dec(0.75)},
+					{Source: "kraken", Count: 1, Weight: dec(1), Share: dec(0.25)},
+				},
+				{
+					{Source: "", Count: 1, Weight: dec(1), Share: dec(1.0 / 6)},
+					{Source: "binance", Count: 1, Weight: dec(3), Share: dec(0.5)},
+					{Source: "kraken", Count: 2, Weight: dec(2), Share: dec(1.0 / 3)},
+				},
+			},
+		},
+		{
+			name:   "tumbling",
+			window: TumblingWindow,
+			want: [][]Contribution{
+				{
+					{Source: "binance", Count: 1, Weight: dec(3), Share: dec(0.75)},
+					{Source: "kraken", Count: 1, Weight: dec(1), Share: dec(0.25)},
+				},
+				{
+					{Source: "", Count: 1, Weight: dec(1), Share: dec(0.5)},
+					{Source: "kraken", Count: 1, Weight: dec(1), Share: dec(0.5)},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tearUp(t)
+			defer tearDown(env)
+
+			WithWindow(tt.window)(env.idxer)
+			sourceWeights(t, env, map[string]string{"binance": "3"})
+
+			var got []Contributions
+			WithContributionHandler(func(c Contributions) {
+				got = append(got, c)
+			})(env.idxer)
+
+			ctx := context.Background()
+			now := time.Now()
+
+			gomock.InOrder(
+				env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+					{Ticker: ticker.BTCUSDTicker, Time: now, Price: "10", Source: "binance"},
+					{Ticker: ticker.BTCUSDTicker, Time: now, Price: "20", Source: "kraken"},
+				}, nil),
+				env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+					{Ticker: ticker.BTCUSDTicker, Time: now, Price: "20", Source: "kraken"},
+					{Ticker: ticker.BTCUSDTicker, Time: now, Price: "40"},
+				}, nil),
+			)
+
+			require.NoError(t, env.idxer.index(ctx, now))
+			require.NoError(t, env.idxer.index(ctx, now))
+
+			require.Len(t, got, len(tt.want))
+			for j, want := range tt.want {
+				assert.Equal(t, ticker.BTCUSDTicker, got[j].Ticker)
+				require.Len(t, got[j].Sources, len(want))
+
+				for k, c := range want {
+					assert.Equal(t, c.Source, got[j].Sources[k].Source)
+					assert.Equal(t, c.Count, got[j].Sources[k].Count)
+					assert.Equal(t, c.Weight.String(), got[j].Sources[k].Weight.String())
+					assert.Equal(t, c.Share.Float64(), got[j].Sources[k].Share.Float64())
+				}
+			}
+
+			assert.Equal(t, "12.5", got[0].Price)
+		})
+	}
+}
+
+func TestIndexer_index_contributions_aggregator(t *testing.T) {
+	tests := []struct {
+		name string
+		agg  AggregatorFactory
+		want []Contribution
+	}{
+		{
+			name: "vwap",
+			agg:  func() Aggregator { return NewVWAP() },
+			want: []Contribution{
+				{Source: "binance", Count: 1, Weight: weight(t, "6"), Share: weight(t, "0.6")},
+				{Source: "kraken", Count: 1, Weight: weight(t, "4"), Share: weight(t, "0.4")},
+			},
+		},
+		{
+			name: "median",
+			agg:  func() Aggregator { return NewMedian() },
+			want: []Contribution{
+				{Source: "binance", Count: 1},
+				{Source: "kraken", Count: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tearUp(t)
+			defer tearDown(env)
+
+			WithAggregator(tt.agg)(env.idxer)
+			sourceWeights(t, env, map[string]string{"binance": "3"})
+
+			var got []Contributions
+			WithContributionHandler(func(c Contributions) {
+				got = append(got, c)
+			})(env.idxer)
+
+			ctx := context.Background()
+			now := time.Now()
+
+			env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+				{Ticker: ticker.BTCUSDTicker, Time: now, Price: "10", Volume: "2", Source: "binance"},
+				{Ticker: ticker.BTCUSDTicker, Time: now, Price: "20", Volume: "4", Source: "kraken"},
+			}, nil)
+
+			require.NoError(t, env.idxer.index(ctx, now))
+
+			require.Len(t, got, 1)
+			require.Len(t, got[0].Sources, len(tt.want))
+
+			for k, c := range tt.want {
+				assert.Equal(t, c.Source, got[0].Sources[k].Source)
+				assert.Equal(t, c.Count, got[0].Sources[k].Count)
+				assert.Equal(t, c.Weight.String(), got[0].Sources[k].Weight.String())
+				assert.Equal(t, c.Share.String(), got[0].Sources[k].Share.String())
+			}
+		})
+	}
+}
@@ -3,42 +3,113 @@ package indexer
 import (
 	"context"
 	"errors"
-	"math"
-	"strconv"
 	"sync"
 	"time"
 
 	"github.com/sschiz/indexer/collecter"
 	"github.com/sschiz/indexer/ticker"
 	"go.uber.org/atomic"
+	"golang.org/x/sync/errgroup"
 )
 
 type Handler func(ticker.Price)
 
+// Option configures Indexer.
+type Option func(*Indexer)
+
+// WithAggregator sets factory of aggregators used to calculate index of each ticker.
+// Mean is used by default.
+func WithAggregator(f AggregatorFactory) Option {
+	return func(i *Indexer) {
+		i.newAggregator = f
+	}
+}
+
+// WithScale sets number of fractional digits of the published index.
+// Negative scale publishes the shortest exact representation, it is used by default.
+func WithScale(scale int32) Option {
+	return func(i *Indexer) {
+		i.scale = scale
+	}
+}
+
+// WithRounding sets rounding mode of the published index.
+// It has effect only with non-negative scale. ticker.RoundHalfEven is used by default.
+func WithRounding(mode ticker.RoundingMode) Option {
+	return func(i *Indexer) {
+		i.rounding = mode
+	}
+}
+
 var (
 	ErrInvalidHandler   = errors.New("invalid handler")
 	ErrInvalidCollecter = errors.New("invalid collecter")
+	ErrInvalidVolume    = errors.New("invalid volume")
+	ErrInvalidRounding  = errors.New("invalid rounding mode")
+	ErrRunContextDone   = errors.New("context of the last start is done")
+
+	// errStopped stops the run group of Indexer on Stop.
+	errStopped = errors.New("stopped")
 )
 
 // Indexer streaming price indexer.
 type Indexer struct {
-	mu   sync.Mutex
-	avgs map[ticker.Ticker]*avg
-
-	handle   Handler
-	interval time.Duration
+	mu            sync.Mutex
+	aggs          map[ticker.Ticker]Aggregator
+	newAggregator AggregatorFactory
+	window        Window
+	lookback      time.Duration
+	samples       map[ticker.Ticker][]Sample // samples of the sliding window
+	last          map[ticker.Ticker]string   // last published index
+	contribs      map[ticker.Ticker]map[string]*Contribution
+	constituents  map[ticker.Ticker][]Constituent // constituents of the current tick
+	maxAge        time.Duration
+	outlierFilter OutlierFilter
+	scale         int32
+	rounding      ticker.RoundingMode
+	minPrice      ticker.Decimal
+	maxPrice      ticker.Decimal
+	quarantine    bool
+	onQuarantine  func(*PriceError)
+	errorPolicy   ErrorPolicy
+	retries       int
+	backoff       time.Duration
+
+	schedulePolicy SchedulePolicy
+	queueSize      int
+
+	handle             Handler
+	handleCandle       CandleHandler
+	handleContribution ContributionHandler
+	handleDetailed     DetailedHandler
+	handleError        ErrorHandler
+	handleLag          TickLagHandler
+	handleExclusion    func(Exclusion)
+	interval           time.Duration
 
 	collecter collecter.Collecter
-	err       error // last error from collecter
-
-	started *atomic.Bool
-	done    chan struct{}
+	err       *atomic.Error // error Indexer stopped with
+
+	stateMu        sync.Mutex
+	state          State
+	hooks          []LifecycleHook
+	changes        []stateChange // transitions not yet delivered to hooks
+	notifying      bool          // set while a goroutine delivers changes to hooks
+	resetOnRestart bool
+	done           chan struct{}   // closed by Stop
+	exited         chan struct{}   // closed when the background loop exits
+	runCtx         context.Context // context of the last Start, reused by Restart
 }
 
 // NewIndexer returns new Indexer instance.
 // Handle is called for each indexed TickerPrice.
 // Interval is a period during which indexing will be carried out.
-func NewIndexer(clctr collecter.Collecter, handle Handler, interval time.Duration) (*Indexer, error) {
+func NewIndexer(
+	clctr collecter.Collecter,
+	handle Handler,
+	interval time.Duration,
+	opts ...Option,
+) (*Indexer, error) {
 	if handle == nil {
 		return nil, ErrInvalidHandler
 	}
@@ -47,79 +118,218 @@ func NewIndexer(clctr collecter.Collecter, handle Handler, interval time.Duratio
 		return nil, ErrInvalidCollecter
 	}
 
-	return &Indexer{
-		collecter: clctr,
-		done:      make(chan struct{}, 1),
-		started:   atomic.NewBool(false),
-		avgs:      make(map[ticker.Ticker]*avg),
-		handle:    handle,
-		interval:  interval,
-	}, nil
+	i := &Indexer{
+		collecter:     clctr,
+		done:          make(chan struct{}),
+		err:           atomic.NewError(nil),
+		exited:        closed(),
+		aggs:          make(map[ticker.Ticker]Aggregator),
+		samples:       make(map[ticker.Ticker][]Sample),
+		last:          make(map[ticker.Ticker]string),
+		contribs:      make(map[ticker.Ticker]map[string]*Contribution),
+		newAggregator: func() Aggregator { return NewMean() },
+		scale:         -1,
+		rounding:      ticker.RoundHalfEven,
+		handle:        handle,
+		interval:      interval,
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	if i.newAggregator == nil {
+		return nil, ErrInvalidAggregator
+	}
+
+	switch i.rounding {
+	case ticker.RoundHalfEven, ticker.RoundHalfUp, ticker.RoundTruncate:
+	default:
+		return nil, ErrInvalidRounding
+	}
+
+	if i.minPrice.Sign() < 0 || (!i.maxPrice.IsZero() && i.maxPrice.Cmp(i.minPrice) < 0) {
+		return nil, ErrInvalidBounds
+	}
+
+	if !i.window.valid() || (i.window == SlidingWindow && i.lookback <= 0) {
+		return nil, ErrInvalidWindow
+	}
+
+	if !i.validPolicy() {
+		return nil, ErrInvalidErrorPolicy
+	}
+
+	if !i.validSchedule() {
+		return nil, ErrInvalidSchedule
+	}
+
+	if i.errorPolicy == SkipTicker {
+		i.quarantine = true
+	}
+
+	return i, nil
 }
 
-// Stop stops Indexer.
+// Stop moves running Indexer to Stopping state and signals it to stop.
+// It does not wait for the running tick, use Wait for that.
+// Stop of Indexer which is not running does nothing.
+// It returns ctx error without stopping if ctx is already done.
 func (i *Indexer) Stop(ctx context.Context) error {
-	if !i.started.Load() {
+	i.stateMu.Lock()
+	if i.state != Running {
+		i.stateMu.Unlock()
 		return nil
 	}
 
-	select {
-	case i.done <- struct{}{}:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
+	if err := ctx.Err(); err != nil {
+		i.stateMu.Unlock()
+		return err
 	}
+
+	i.state = Stopping
+	i.changed(Running, Stopping)
+	close(i.done)
+	i.stateMu.Unlock()
+
+	i.notify()
+
+	return nil
 }
 
-// Start starts Indexer.
+// Start starts Created or Stopped Indexer.
+// Start of Indexer which is running or stopping does nothing.
+// Aggregated state is preserved across restarts unless WithResetOnRestart is set.
 func (i *Indexer) Start(ctx context.Context) {
-	if i.started.Load() {
+	i.stateMu.Lock()
+
+	from := i.state
+	if from != Created && from != Stopped {
+		i.stateMu.Unlock()
 		return
 	}
 
-	go i.start(ctx)
+	reset := from == Stopped && i.resetOnRestart
+
+	i.err.Store(nil)
+	i.state = Running
+	i.done = make(chan struct{})
+	i.exited = make(chan struct{})
+	i.runCtx = ctx
+	i.changed(from, Running)
+	exited := i.exited
+
+	i.stateMu.Unlock()
 
-	i.started.Store(true)
+	if reset {
+		i.reset()
+	}
+
+	i.notify()
+
+	go func() {
+		defer close(exited)
+		i.start(ctx)
+		i.transition(Stopping, Stopped)
+	}()
 }
 
 // Err returns error
 // if any of that returned while collecting.
+// It is safe to call concurrently.
 func (i *Indexer) Err() error {
-	return i.err
+	return i.err.Load()
+}
+
+// Done returns a channel which is closed when the background loop
+// and all its ticks have exited. It is closed if Indexer is not started.
+func (i *Indexer) Done() <-chan struct{} {
+	i.stateMu.Lock()
+	defer i.stateMu.Unlock()
+
+	return i.exited
 }
 
+// Wait blocks until the background loop and all its ticks have exited
+// and returns the error Indexer stopped with.
+// It returns ctx error if ctx is done first.
+func (i *Indexer) Wait(ctx context.Context) error {
+	select {
+	case <-i.Done():
+		return i.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// start runs the ticker loop and the worker in a group and waits until both exit.
+// The loop exits on Stop, ctx cancellation or failure of the worker,
+// the worker completes the running tick and exits after it.
 func (i *Indexer) start(ctx context.Context) {
+	g, gctx := errgroup.WithContext(context.Background())
+	sched := i.newScheduler()
+
+	g.Go(func() error {
+		defer i.transition(Running, Stopping)
+		return i.loop(ctx, gctx, sched)
+	})
+
+	var tickErr error
+
+	g.Go(func() error {
+		tickErr = i.work(ctx, gctx, sched.ticks)
+		return tickErr
+	})
+
+	err := g.Wait()
+	if errors.Is(err, errStopped) {
+		// a tick may fail after Stop, its error must not be hidden.
+		err = tickErr
+	}
+
+	i.err.Store(err)
+}
+
+// loop schedules ticks at each interval until Indexer is stopped,
+// ctx is done or the group is canceled.
+func (i *Indexer) loop(ctx, gctx context.Context, sched *scheduler) error {
 	t := time.NewTicker(i.interval)
 	defer t.Stop()
-	defer i.started.Store(false)
-
-	errs := make(chan error)
 
 	for {
 		select {
 		case t := <-t.C:
-			go func() {
-				err := i.index(ctx, t)
-				if err != nil {
-					errs <- err
-					return
-				}
-			}()
+			sched.schedule(t)
 		case <-i.done:
-			return
+			return errStopped
 		case <-ctx.Done():
-			if i.err == nil {
-				i.err = ctx.Err()
-			}
-			return
-		case err := <-errs:
-			i.err = err
-			return
+			return ctx.Err()
+		case <-gctx.Done():
+			return nil
 		}
 	}
 }
 
-const bitSize = 64
+// work runs scheduled ticks one by one until Indexer is stopped, the group is canceled or a tick fails.
+// Ticks use ctx, so the running one is not interrupted by Stop.
+func (i *Indexer) work(ctx, gctx context.Context, ticks <-chan scheduledTick) error {
+	for {
+		select {
+		case t := <-ticks:
+			if gctx.Err() != nil || i.stopped() {
+				return nil
+			}
+
+			i.lag(t)
+
+			if err := i.tick(ctx, t.time); err != nil {
+				return err
+			}
+		case <-gctx.Done():
+			return nil
+		}
+	}
+}
 
 func (i *Indexer) index(ctx context.Context, t time.Time) error {
 	i.mu.Lock()
@@ -130,46 +340,171 @@ func (i *Indexer) index(ctx context.Context, t time.Time) error {
 		return err
 	}
 
-	for _, price := range prices {
-		p, err := strconv.ParseFloat(price.Price, bitSize)
-		if err != nil {
-			return err
+	if l, ok := i.collecter.(collecter.TickerLister); ok {
+		i.prune(l.Tickers())
+	}
+
+	i.constituents = make(map[ticker.Ticker][]Constituent)
+
+	samples, quarantined, err := i.validate(prices)
+	if err != nil {
+		return err
+	}
+
+	stale := i.dropStale(samples, t)
+	i.excludeOutliers(samples)
+	i.include(samples)
+
+	for tk, ss := range samples {
+		if i.handleCandle != nil && len(ss) > 0 {
+			i.handleCandle(i.candle(tk, ss, t))
 		}
 
-		if _, ok := i.avgs[price.Ticker]; !ok {
-			i.avgs[price.Ticker] = &avg{
-				num: 1,
-				sum: p,
+		for _, s := range ss {
+			if i.window == SlidingWindow {
+				i.keep(tk, s, t)
+				continue
 			}
-			continue
+
+			i.add(tk, s)
 		}
+	}
 
-		i.avgs[price.Ticker] = i.avgs[price.Ticker].Add(p)
+	if i.window == SlidingWindow {
+		i.slide(t)
+	}
+
+	if i.handleDetailed != nil {
+		for tk := range quarantined {
+			i.handleDetailed(ticker.Price{Ticker: tk, Time: t}, i.constituents[tk])
+		}
 	}
 
-	for k, v := range i.avgs {
-		i.handle(ticker.Price{
+	for k, agg := range i.aggs {
+		if _, ok := quarantined[k]; ok {
+			continue
+		}
+
+		if a, ok := agg.(Advancer); ok {
+			a.Advance(t)
+		}
+
+		_, isStale := stale[k]
+
+		v, err := agg.Value()
+		switch {
+		case errors.Is(err, ErrNoSamples):
+			if !isStale {
+				continue
+			}
+
+			// Ticker which has never been published has no last value.
+			last := i.last[k]
+
+			i.publish(ticker.Price{
+				Ticker: k,
+				Time:   t,
+				Price:  last,
+				Stale:  true,
+			})
+
+			if i.handleContribution != nil {
+				i.handleContribution(i.contributions(k, t, last, true))
+			}
+
+			continue
+		case err != nil:
+			return err
+		}
+
+		price := i.format(v)
+		i.last[k] = price
+
+		i.publish(ticker.Price{
 			Ticker: k,
 			Time:   t,
-			Price:  strconv.FormatFloat(v.Average(), 'f', -1, bitSize),
+			Price:  price,
+			Stale:  isStale,
 		})
+
+		if i.handleContribution != nil {
+			i.handleContribution(i.contributions(k, t, price, isStale))
+		}
+	}
+
+	if i.window == TumblingWindow {
+		for tk, agg := range i.aggs {
+			agg.Reset()
+			delete(i.contribs, tk)
+		}
 	}
 
 	return nil
 }
 
-type avg struct {
-	sum float64
-	num float64
+func (i *Indexer) format(v ticker.Decimal) string {
+	if i.scale < 0 {
+		return v.String()
+	}
+
+	return v.StringFixed(i.scale, i.rounding)
 }
 
-func (a *avg) Add(b float64) *avg {
-	a.sum += math.Abs(b)
-	a.num++
+// prune drops state of tickers which are not listed.
+func (i *Indexer) prune(tickers []ticker.Ticker) {
+	listed := make(map[ticker.Ticker]struct{}, len(tickers))
+	for _, tk := range tickers {
+		listed[tk] = struct{}{}
+	}
+
+	for tk := range i.aggs {
+		if _, ok := listed[tk]; !ok {
+			delete(i.aggs, tk)
+		}
+	}
 
-	return a
+	for tk := range i.samples {
+		if _, ok := listed[tk]; !ok {
+			delete(i.samples, tk)
+		}
+	}
+
+	for tk := range i.last {
+		if _, ok := listed[tk]; !ok {
+			delete(i.last, tk)
+		}
+	}
+
+	for tk := range i.contribs {
+		if _, ok := listed[tk]; !ok {
+			delete(i.contribs, tk)
+		}
+	}
+}
+
+// stopped reports whether Stop has been called since Start.
+func (i *Indexer) stopped() bool {
+	select {
+	case <-i.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func closed() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+
+	return c
 }
 
-func (a avg) Average() float64 {
-	return a.sum / a.num
+func (i *Indexer) aggregator(tk ticker.Ticker) Aggregator {
+	agg, ok := i.aggs[tk]
+	if !ok {
+		agg = i.newAggregator()
+		i.aggs[tk] = agg
+	}
+
+	return agg
 }
@@ -69,6 +69,40 @@ func TestNewIndexer(t *testing.T) {
 		assert.ErrorIs(t, err, ErrInvalidHandler)
 	})
 
+	t.Run("invalid aggregator", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		clctr := mock.NewMockCollecter(ctrl)
+
+		got, err := NewIndexer(
+			clctr,
+			func(tp ticker.Price) { t.Log(tp) },
+			time.Minute,
+			WithAggregator(nil),
+		)
+
+		require.Nil(t, got)
+		assert.ErrorIs(t, err, ErrInvalidAggregator)
+	})
+
+	t.Run("invalid rounding", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		clctr := mock.NewMockCollecter(ctrl)
+
+		got, err := NewIndexer(
+			clctr,
+			func(tp ticker.Price) { t.Log(tp) },
+			time.Minute,
+			WithRounding(ticker.RoundingMode(-1)),
+		)
+
+		require.Nil(t, got)
+		assert.ErrorIs(t, err, ErrInvalidRounding)
+	})
+
 	t.Run("success", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -85,11 +119,10 @@ func TestNewIndexer(t *testing.T) {
 		require.NoError(t, err)
 
 		expected := Indexer{
-			avgs:      make(map[ticker.Ticker]*avg),
+			aggs:      make(map[ticker.Ticker]Aggregator),
 			handle:    handler,
 			collecter: clctr,
-			err:       nil,
-			started:   atomic.NewBool(false),
+			err:       atomic.NewError(nil),
 			done:      make(chan struct{}),
 			interval:  time.Minute,
 		}
@@ -97,8 +130,8 @@ func TestNewIndexer(t *testing.T) {
 		assert.Condition(t, func() (success bool) {
 			return got.done != nil &&
 				assert.ObjectsAreEqualValues(
-					expected.avgs,
-					got.avgs,
+					expected.aggs,
+					got.aggs,
 				) &&
 				assert.ObjectsAreEqual(
 					expected.collecter,
@@ -113,8 +146,8 @@ func TestNewIndexer(t *testing.T) {
 					got.interval,
 				) &&
 				assert.ObjectsAreEqual(
-					expected.started,
-					got.started,
+					expected.state,
+					got.state,
 				)
 		})
 	})
@@ -139,20 +172,21 @@ func TestIndexer_Stop(t *testing.T) {
 		env := tearUp(t)
 		defer tearDown(env)
 
-		env.idxer.started.Store(true)
+		env.idxer.state = Running
 
 		err := env.idxer.Stop(context.Background())
 		require.NoError(t, err)
 
-		assert.Equal(t, <-env.idxer.done, struct{}{})
+		_, ok := <-env.idxer.done
+		assert.False(t, ok)
+		assert.Equal(t, Stopping, env.idxer.State())
 	})
 
 	t.Run("canceled context", func(t *testing.T) {
 		env := tearUp(t)
 		defer tearDown(env)
 
-		env.idxer.started.Store(true)
-		env.idxer.done = make(chan struct{})
+		env.idxer.state = Running
 
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel()
@@ -160,6 +194,7 @@ func TestIndexer_Stop(t *testing.T) {
 		err := env.idxer.Stop(ctx)
 
 		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, Running, env.idxer.State())
 	})
 }
 
@@ -173,7 +208,7 @@ func TestIndexer_Start(t *testing.T) {
 			called = true
 		}
 
-		env.idxer.started.Store(true)
+		env.idxer.state = Running
 
 		env.idxer.Start(context.Background())
 
@@ -189,7 +224,7 @@ func TestIndexer_Start(t *testing.T) {
 
 		env.idxer.Start(ctx)
 
-		assert.True(t, env.idxer.started.Load())
+		assert.Equal(t, Running, env.idxer.State())
 	})
 }
 
@@ -200,7 +235,7 @@ func TestIndexer_Err(t *testing.T) {
 		env := tearUp(t)
 		defer tearDown(env)
 
-		env.idxer.err = expectedErr
+		env.idxer.err.Store(expectedErr)
 
 		assert.ErrorIs(t, env.idxer.Err(), expectedErr)
 	})
@@ -213,6 +248,69 @@ func TestIndexer_Err(t *testing.T) {
 	})
 }
 
+func TestIndexer_Wait(t *testing.T) {
+	t.Run("not started", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		assert.NoError(t, env.idxer.Wait(context.Background()))
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		env.idxer.state = Running
+		env.idxer.exited = make(chan struct{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.ErrorIs(t, env.idxer.Wait(ctx), context.Canceled)
+	})
+
+	t.Run("final tick completed", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		env.idxer.interval = time.Millisecond
+		WithWindow(TumblingWindow)(env.idxer)
+
+		ticked := make(chan struct{})
+		release := make(chan struct{})
+		handled := atomic.NewBool(false)
+
+		env.idxer.handle = func(ticker.Price) {
+			close(ticked)
+			<-release
+			handled.Store(true)
+		}
+
+		ctx := context.Background()
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Price: "1"},
+		}, nil)
+		env.collecter.EXPECT().Collect(ctx).Return(nil, nil).AnyTimes()
+
+		env.idxer.Start(ctx)
+		<-ticked
+
+		require.NoError(t, env.idxer.Stop(ctx))
+
+		select {
+		case <-env.idxer.Done():
+			t.Fatal("done before the tick completed")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		close(release)
+
+		require.NoError(t, env.idxer.Wait(ctx))
+		assert.True(t, handled.Load())
+		assert.Equal(t, Stopped, env.idxer.State())
+	})
+}
+
 func TestIndexer_start(t *testing.T) {
 	t.Run("canceled context", func(t *testing.T) {
 		env := tearUp(t)
@@ -221,10 +319,11 @@ func TestIndexer_start(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel()
 
+		env.idxer.state = Running
 		env.idxer.start(ctx)
 
-		assert.ErrorIs(t, env.idxer.err, context.Canceled)
-		assert.False(t, env.idxer.started.Load())
+		assert.ErrorIs(t, env.idxer.Err(), context.Canceled)
+		assert.Equal(t, Stopping, env.idxer.State())
 	})
 	t.Run("stopped", func(t *testing.T) {
 		env := tearUp(t)
@@ -233,11 +332,12 @@ func TestIndexer_start(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		env.idxer.done <- struct{}{}
+		env.idxer.state = Running
+		close(env.idxer.done)
 		env.idxer.start(ctx)
 
-		assert.NoError(t, env.idxer.err)
-		assert.False(t, env.idxer.started.Load())
+		assert.NoError(t, env.idxer.Err())
+		assert.Equal(t, Stopping, env.idxer.State())
 	})
 	t.Run("collecter error", func(t *testing.T) {
 		env := tearUp(t)
@@ -251,10 +351,52 @@ func TestIndexer_start(t *testing.T) {
 		expectedErr := errors.New("any error")
 		env.collecter.EXPECT().Collect(ctx).Return(nil, expectedErr)
 
+		env.idxer.state = Running
+		env.idxer.start(ctx)
+
+		assert.ErrorIs(t, env.idxer.Err(), expectedErr)
+		assert.Equal(t, Stopping, env.idxer.State())
+	})
+	t.Run("retries exhausted after stop", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		env.idxer.interval = time.Millisecond
+		WithRetry(1, time.Millisecond)(env.idxer)
+
+		expectedErr := errors.New("any error")
+		gomock.InOrder(
+			env.collecter.EXPECT().Collect(ctx).Return(nil, expectedErr),
+			env.collecter.EXPECT().Collect(ctx).DoAndReturn(func(context.Context) ([]*ticker.Price, error) {
+				close(env.idxer.done)
+				// let the loop exit before the tick fails.
+				time.Sleep(10 * time.Millisecond)
+
+				return nil, expectedErr
+			}),
+		)
+
+		env.idxer.state = Running
 		env.idxer.start(ctx)
 
-		assert.ErrorIs(t, env.idxer.err, expectedErr)
-		assert.False(t, env.idxer.started.Load())
+		assert.ErrorIs(t, env.idxer.Err(), expectedErr)
+	})
+}
+
+func TestIndexer_work(t *testing.T) {
+	t.Run("stopped with queued tick", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		ticks := make(chan scheduledTick, 1)
+		ticks <- scheduledTick{time: time.Now()}
+
+		// Collect is not expected.
+		close(env.idxer.done)
+		assert.NoError(t, env.idxer.work(context.Background(), context.Background(), ticks))
 	})
 }
 
@@ -294,6 +436,32 @@ func TestIndexer_index(t *testing.T) {
 		assert.ErrorIs(t, err, strconv.ErrSyntax)
 	})
 
+	t.Run("invalid volume", func(t *testing.T) {
+		for volume, expectedErr := range map[string]error{
+			"invalid num": strconv.ErrSyntax,
+			"-1":          ErrInvalidVolume,
+		} {
+			env := tearUp(t)
+
+			ctx := context.Background()
+
+			env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+				{
+					Ticker: ticker.BTCUSDTicker,
+					Time:   time.Now(),
+					Price:  "1",
+					Volume: volume,
+				},
+			}, nil)
+
+			err := env.idxer.index(ctx, time.Now())
+
+			assert.ErrorIs(t, err, expectedErr)
+
+			tearDown(env)
+		}
+	})
+
 	t.Run("indexed successfully", func(t *testing.T) {
 		env := tearUp(t)
 		defer tearDown(env)
@@ -334,68 +502,162 @@ func TestIndexer_index(t *testing.T) {
 		}
 		assert.ElementsMatch(t, expected, got)
 	})
+
+	t.Run("custom aggregator", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		now := time.Now().UTC()
+
+		var got []ticker.Price
+		env.idxer.handle = func(tp ticker.Price) {
+			got = append(got, tp)
+		}
+		WithAggregator(func() Aggregator { return NewMax() })(env.idxer)
+
+		ctx := context.Background()
+
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{
+				Ticker: ticker.BTCUSDTicker,
+				Time:   now,
+				Price:  "1",
+			},
+			{
+				Ticker: ticker.BTCUSDTicker,
+				Time:   now,
+				Price:  "3",
+			},
+		}, nil)
+
+		err := env.idxer.index(ctx, now)
+
+		require.NoError(t, err)
+
+		expected := []ticker.Price{
+			{
+				Ticker: ticker.BTCUSDTicker,
+				Time:   now,
+				Price:  "3",
+			},
+		}
+		assert.Equal(t, expected, got)
+	})
 }
 
-func Test_avg_Add(t *testing.T) {
+func TestIndexer_index_decimal(t *testing.T) {
 	tests := []struct {
-		name string
-		args []float64
-		want avg
+		name   string
+		opts   []Option
+		prices []string
+		want   string
 	}{
 		{
-			name: "positive",
-			args: []float64{1, 2, 3, 4},
-			want: avg{
-				sum: 10,
-				num: 4,
-			},
+			name:   "exact",
+			prices: []string{"0.1", "0.2"},
+			want:   "0.15",
 		},
 		{
-			name: "negative",
-			args: []float64{-1, -2, -3, -4},
-			want: avg{
-				sum: 10,
-				num: 4,
-			},
+			name:   "non-terminating",
+			prices: []string{"1", "1", "2"},
+			want:   "1.333333333333333333",
+		},
+		{
+			name:   "half even",
+			opts:   []Option{WithScale(2)},
+			prices: []string{"1.005"},
+			want:   "1.00",
+		},
+		{
+			name:   "half up",
+			opts:   []Option{WithScale(2), WithRounding(ticker.RoundHalfUp)},
+			prices: []string{"1.005"},
+			want:   "1.01",
+		},
+		{
+			name:   "truncate",
+			opts:   []Option{WithScale(0), WithRounding(ticker.RoundTruncate)},
+			prices: []string{"1.9"},
+			want:   "1",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			a := &avg{}
-			for _, v := range tt.args {
-				a.Add(v)
+			env := tearUp(t)
+			defer tearDown(env)
+
+			for _, opt := range tt.opts {
+				opt(env.idxer)
+			}
+
+			var got []string
+			env.idxer.handle = func(tp ticker.Price) {
+				got = append(got, tp.Price)
+			}
+
+			ctx := context.Background()
+			now := time.Now()
+
+			prices := make([]*ticker.Price, 0, len(tt.prices))
+			for _, p := range tt.prices {
+				prices = append(prices, &ticker.Price{Ticker: ticker.BTCUSDTicker, Time: now, Price: p})
 			}
 
-			assert.Equal(t, tt.want, *a)
+			env.collecter.EXPECT().Collect(ctx).Return(prices, nil)
+
+			require.NoError(t, env.idxer.index(ctx, now))
+			assert.Equal(t, []string{tt.want}, got)
 		})
 	}
 }
 
-func Test_avg_Average(t *testing.T) {
-	tests := []struct {
-		name string
-		args []float64
-		want float64
-	}{
-		{
-			name: "positive",
-			args: []float64{2, 4, 8, 10},
-			want: 6,
-		},
-		{
-			name: "negative",
-			args: []float64{-2, -4, -8, -10},
-			want: 6,
-		},
+type listingCollecter struct {
+	*mock.MockCollecter
+	tickers []ticker.Ticker
+}
+
+func (c *listingCollecter) Tickers() []ticker.Ticker {
+	return c.tickers
+}
+
+func TestIndexer_index_prune(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	clctr := &listingCollecter{
+		MockCollecter: env.collecter,
+		tickers:       []ticker.Ticker{ticker.BTCUSDTicker, ethUSDTicker},
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			a := &avg{}
-			for _, v := range tt.args {
-				a.Add(v)
-			}
+	env.idxer.collecter = clctr
 
-			assert.Equal(t, tt.want, a.Average())
-		})
+	var got []ticker.Price
+	env.idxer.handle = func(tp ticker.Price) {
+		got = append(got, tp)
 	}
+
+	ctx := context.Background()
+	now := time.Now()
+
+	gomock.InOrder(
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "1"},
+			{Ticker: ethUSDTicker, Time: now, Price: "2"},
+		}, nil),
+		env.collecter.EXPECT().Collect(ctx).DoAndReturn(func(context.Context) ([]*ticker.Price, error) {
+			clctr.tickers = []ticker.Ticker{ticker.BTCUSDTicker}
+
+			return []*ticker.Price{
+				{Ticker: ticker.BTCUSDTicker, Time: now, Price: "1"},
+			}, nil
+		}),
+	)
+
+	require.NoError(t, env.idxer.index(ctx, now))
+	require.Len(t, got, 2)
+
+	got = nil
+
+	require.NoError(t, env.idxer.index(ctx, now))
+	assert.Equal(t, []ticker.Price{{Ticker: ticker.BTCUSDTicker, Time: now, Price: "1"}}, got)
+	assert.NotContains(t, env.idxer.aggs, ethUSDTicker)
 }
@@ -0,0 +1,218 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+// knownGoroutines are parts of stacks of goroutines which are not started by Indexer.
+var knownGoroutines = []string{
+	"testing.tRunner",
+	"testing.(*T).Run",
+	"os/signal.signal_recv",
+}
+
+// checkGoroutines returns a function which fails the test
+// if goroutines started after checkGoroutines is called are still running.
+// Goroutines are compared by their stacks, known ones are ignored.
+// Exited goroutines are awaited for a second.
+func checkGoroutines(t *testing.T) func() {
+	t.Helper()
+
+	before := goroutines()
+
+	return func() {
+		t.Helper()
+
+		deadline := time.Now().Add(time.Second)
+		for {
+			leaked := leakedGoroutines(before)
+			if len(leaked) == 0 {
+				return
+			}
+
+			if time.Now().After(deadline) {
+				t.Errorf("leaked %d goroutines:\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+				return
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// goroutines returns stacks of all goroutines by their headers, e.g. "goroutine 7".
+func goroutines() map[string]string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := make(map[string]string)
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		header, _, _ := strings.Cut(stack, " [")
+		stacks[header] = stack
+	}
+
+	return stacks
+}
+
+// leakedGoroutines returns stacks of unknown goroutines which are not in before.
+func leakedGoroutines(before map[string]string) []string {
+	var leaked []string
+
+	for header, stack := range goroutines() {
+		if _, ok := before[header]; ok || knownGoroutine(stack) {
+			continue
+		}
+
+		leaked = append(leaked, stack)
+	}
+
+	return leaked
+}
+
+func knownGoroutine(stack string) bool {
+	for _, known := range knownGoroutines {
+		if strings.Contains(stack, known) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestIndexer_start_leaks(t *testing.T) {
+	anyErr := errors.New("any error")
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		stop    func(env *testEnv, cancel context.CancelFunc)
+		stopped bool // Stop is called, so no Collect may start after it
+		collect func() ([]*ticker.Price, error)
+		wantErr error
+	}{
+		{
+			name: "stop",
+			stop: func(env *testEnv, _ context.CancelFunc) {
+				require.NoError(t, env.idxer.Stop(context.Background()))
+			},
+			stopped: true,
+			collect: func() ([]*ticker.Price, error) {
+				return []*ticker.Price{{Ticker: ticker.BTCUSDTicker, Price: "1"}}, nil
+			},
+		},
+		{
+			name: "canceled context",
+			stop: func(_ *testEnv, cancel context.CancelFunc) {
+				cancel()
+			},
+			collect: func() ([]*ticker.Price, error) {
+				return nil, nil
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name: "stop while failing",
+			opts: []Option{WithErrorPolicy(SkipTick), WithTickQueue(10)},
+			stop: func(env *testEnv, _ context.CancelFunc) {
+				require.NoError(t, env.idxer.Stop(context.Background()))
+			},
+			stopped: true,
+			collect: func() ([]*ticker.Price, error) {
+				return nil, anyErr
+			},
+		},
+		{
+			name: "stop during retry",
+			opts: []Option{WithRetry(3, time.Hour), WithTickQueue(10)},
+			stop: func(env *testEnv, _ context.CancelFunc) {
+				require.NoError(t, env.idxer.Stop(context.Background()))
+			},
+			stopped: true,
+			collect: func() ([]*ticker.Price, error) {
+				return nil, anyErr
+			},
+			wantErr: anyErr,
+		},
+		{
+			name: "tick error",
+			opts: []Option{WithTickQueue(10)},
+			stop: func(*testEnv, context.CancelFunc) {},
+			collect: func() ([]*ticker.Price, error) {
+				return nil, anyErr
+			},
+			wantErr: anyErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tearUp(t)
+			defer tearDown(env)
+
+			for _, opt := range tt.opts {
+				opt(env.idxer)
+			}
+
+			env.idxer.interval = time.Millisecond
+
+			var calls atomic.Int32
+
+			ticked := make(chan struct{}, 1)
+			env.collecter.EXPECT().Collect(gomock.Any()).DoAndReturn(func(context.Context) ([]*ticker.Price, error) {
+				calls.Inc()
+
+				select {
+				case ticked <- struct{}{}:
+				default:
+				}
+
+				time.Sleep(2 * time.Millisecond)
+
+				return tt.collect()
+			}).MinTimes(1)
+
+			check := checkGoroutines(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			env.idxer.Start(ctx)
+			<-ticked
+
+			tt.stop(env, cancel)
+			started := calls.Load()
+
+			err := env.idxer.Wait(context.Background())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if tt.stopped {
+				assert.Equal(t, started, calls.Load())
+			}
+
+			assert.Equal(t, Stopped, env.idxer.State())
+			check()
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/sschiz/indexer/ticker"
+)
+
+// State is a lifecycle state of Indexer.
+// Indexer goes Created → Running → Stopping → Stopped and can be started again from Stopped.
+type State int
+
+const (
+	// Created is a state of Indexer which has never been started.
+	Created State = iota
+	// Running is a state of Indexer which indexes at each interval.
+	Running
+	// Stopping is a state of Indexer which is completing its running ticks.
+	Stopping
+	// Stopped is a state of Indexer which has completed all its ticks.
+	Stopped
+)
+
+func (s State) String() string {
+	switch s {
+	case Created:
+		return "created"
+	case Running:
+		return "running"
+	case Stopping:
+		return "stopping"
+	case Stopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// LifecycleHook is called on each state transition of Indexer.
+type LifecycleHook func(from, to State)
+
+// stateChange is a state transition to deliver to hooks.
+type stateChange struct {
+	from, to State
+}
+
+// WithLifecycleHook adds hook which is called on each state transition.
+// Hooks are called in order they were added, Indexer may be in the next state already.
+// Hooks are never called concurrently and see transitions in the order they happened,
+// so a hook may be called on another goroutine after the transition returned.
+// Hooks may start and stop Indexer, those transitions are delivered after the running hook returns.
+func WithLifecycleHook(hook LifecycleHook) Option {
+	return func(i *Indexer) {
+		i.hooks = append(i.hooks, hook)
+	}
+}
+
+// WithResetOnRestart makes Indexer drop aggregated state when it is started again after Stop.
+// By default the state is preserved, so the index continues from where it stopped.
+func WithResetOnRestart() Option {
+	return func(i *Indexer) {
+		i.resetOnRestart = true
+	}
+}
+
+// State returns the current state of Indexer.
+func (i *Indexer) State() State {
+	i.stateMu.Lock()
+	defer i.stateMu.Unlock()
+
+	return i.state
+}
+
+// Restart stops Indexer, waits until it is stopped and starts it again
+// with the context it was last started with, ctx bounds only the wait.
+// Indexer which is not running is just started, with context.Background if it has never been.
+// It returns ctx error if ctx is done before Indexer is stopped
+// and ErrRunContextDone without starting if the context of the last start is done.
+func (i *Indexer) Restart(ctx context.Context) error {
+	if err := i.Stop(ctx); err != nil {
+		return err
+	}
+
+	select {
+	case <-i.Done():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	i.stateMu.Lock()
+	runCtx := i.runCtx
+	i.stateMu.Unlock()
+
+	if runCtx == nil {
+		runCtx = context.Background()
+	}
+
+	if runCtx.Err() != nil {
+		return ErrRunContextDone
+	}
+
+	i.Start(runCtx)
+
+	return nil
+}
+
+// transition moves Indexer from one state to another if it is in the former.
+func (i *Indexer) transition(from, to State) {
+	i.stateMu.Lock()
+	if i.state != from {
+		i.stateMu.Unlock()
+		return
+	}
+
+	i.state = to
+	i.changed(from, to)
+	i.stateMu.Unlock()
+
+	i.notify()
+}
+
+// changed records transition to deliver to hooks, stateMu must be held.
+func (i *Indexer) changed(from, to State) {
+	if len(i.hooks) > 0 {
+		i.changes = append(i.changes, stateChange{from: from, to: to})
+	}
+}
+
+// notify delivers recorded transitions to hooks one by one,
+// unless another goroutine delivers them already.
+func (i *Indexer) notify() {
+	i.stateMu.Lock()
+	if i.notifying {
+		i.stateMu.Unlock()
+		return
+	}
+
+	i.notifying = true
+
+	for len(i.changes) > 0 {
+		c := i.changes[0]
+		i.changes = i.changes[1:]
+		i.stateMu.Unlock()
+
+		for _, hook := range i.hooks {
+			hook(c.from, c.to)
+		}
+
+		i.stateMu.Lock()
+	}
+
+	i.notifying = false
+	i.stateMu.Unlock()
+}
+
+// reset drops aggregated state.
+func (i *Indexer) reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.aggs = make(map[ticker.Ticker]Aggregator)
+	i.samples = make(map[ticker.Ticker][]Sample)
+	i.last = make(map[ticker.Ticker]string)
+	i.contribs = make(map[ticker.Ticker]map[string]*Contribution)
+}
@@ -0,0 +1,282 @@
+package indexer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState_String(t *testing.T) {
+	assert.Equal(t, "created", Created.String())
+	assert.Equal(t, "running", Running.String())
+	assert.Equal(t, "stopping", Stopping.String())
+	assert.Equal(t, "stopped", Stopped.String())
+	assert.Equal(t, "unknown", State(42).String())
+}
+
+func TestIndexer_lifecycle(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	type transition struct{ from, to State }
+
+	var (
+		mu          sync.Mutex
+		transitions []transition
+	)
+
+	WithLifecycleHook(func(from, to State) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		transitions = append(transitions, transition{from: from, to: to})
+	})(env.idxer)
+
+	env.idxer.interval = time.Millisecond
+	env.collecter.EXPECT().Collect(gomock.Any()).Return(nil, nil).AnyTimes()
+
+	ctx := context.Background()
+
+	assert.Equal(t, Created, env.idxer.State())
+
+	env.idxer.Start(ctx)
+	assert.Equal(t, Running, env.idxer.State())
+
+	require.NoError(t, env.idxer.Restart(ctx))
+	assert.Equal(t, Running, env.idxer.State())
+
+	require.NoError(t, env.idxer.Stop(ctx))
+	require.NoError(t, env.idxer.Wait(ctx))
+	assert.Equal(t, Stopped, env.idxer.State())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, []transition{
+		{from: Created, to: Running},
+		{from: Running, to: Stopping},
+		{from: Stopping, to: Stopped},
+		{from: Stopped, to: Running},
+		{from: Running, to: Stopping},
+		{from: Stopping, to: Stopped},
+	}, transitions)
+}
+
+func TestIndexer_Start_restart(t *testing.T) {
+	tests := []struct {
+		name  string
+		reset bool
+		want  string
+	}{
+		{
+			name: "preserved",
+			want: "2",
+		},
+		{
+			name:  "reset",
+			reset: true,
+			want:  "3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tearUp(t)
+			defer tearDown(env)
+
+			if tt.reset {
+				WithResetOnRestart()(env.idxer)
+			}
+
+			var got []ticker.Price
+			env.idxer.handle = func(tp ticker.Price) {
+				got = append(got, tp)
+			}
+
+			ctx := context.Background()
+			now := time.Now()
+
+			env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+				{Ticker: ticker.BTCUSDTicker, Price: "1"},
+			}, nil)
+			require.NoError(t, env.idxer.index(ctx, now))
+
+			// Restart without ticks.
+			env.idxer.state = Stopped
+			env.idxer.interval = time.Hour
+			env.idxer.Start(ctx)
+			require.NoError(t, env.idxer.Stop(ctx))
+			require.NoError(t, env.idxer.Wait(ctx))
+
+			env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+				{Ticker: ticker.BTCUSDTicker, Price: "3"},
+			}, nil)
+			require.NoError(t, env.idxer.index(ctx, now))
+
+			require.Len(t, got, 2)
+			assert.Equal(t, tt.want, got[1].Price)
+		})
+	}
+}
+
+func TestIndexer_Restart_context(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	env.idxer.interval = time.Millisecond
+
+	type key struct{}
+
+	runCtx, cancelRun := context.WithCancel(context.WithValue(context.Background(), key{}, "run"))
+	defer cancelRun()
+
+	collected := make(chan context.Context, 1)
+	env.collecter.EXPECT().Collect(gomock.Any()).DoAndReturn(func(ctx context.Context) ([]*ticker.Price, error) {
+		select {
+		case collected <- ctx:
+		default:
+		}
+
+		return nil, nil
+	}).AnyTimes()
+
+	env.idxer.Start(runCtx)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, env.idxer.Restart(ctx))
+	cancel()
+
+	// Canceled ctx of Restart does not stop the new run.
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, Running, env.idxer.State())
+
+	select {
+	case ctx := <-collected:
+		assert.Equal(t, "run", ctx.Value(key{}))
+	case <-time.After(time.Second):
+		t.Fatal("no tick after restart")
+	}
+
+	require.NoError(t, env.idxer.Stop(context.Background()))
+	require.NoError(t, env.idxer.Wait(context.Background()))
+}
+
+func TestIndexer_lifecycle_hooksOrder(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	type transition struct{ from, to State }
+
+	var (
+		mu          sync.Mutex
+		calls       int
+		concurrent  bool
+		transitions []transition
+	)
+
+	WithLifecycleHook(func(from, to State) {
+		mu.Lock()
+		calls++
+		concurrent = concurrent || calls > 1
+		transitions = append(transitions, transition{from: from, to: to})
+		mu.Unlock()
+
+		// Give concurrent transitions a chance to overtake.
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		calls--
+		mu.Unlock()
+	})(env.idxer)
+
+	env.idxer.interval = time.Millisecond
+	env.collecter.EXPECT().Collect(gomock.Any()).Return(nil, nil).AnyTimes()
+
+	ctx := context.Background()
+
+	for j := 0; j < 10; j++ {
+		env.idxer.Start(ctx)
+		require.NoError(t, env.idxer.Stop(ctx))
+		require.NoError(t, env.idxer.Wait(ctx))
+	}
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(transitions) == 30 && calls == 0
+	}, time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.False(t, concurrent)
+
+	from := Created
+	for _, tr := range transitions {
+		assert.Equal(t, from, tr.from)
+		from = tr.to
+	}
+
+	assert.Equal(t, Stopped, from)
+}
+
+func TestIndexer_lifecycle_hookStops(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	type transition struct{ from, to State }
+
+	var transitions []transition
+
+	ctx := context.Background()
+
+	WithLifecycleHook(func(from, to State) {
+		transitions = append(transitions, transition{from: from, to: to})
+
+		if to == Running {
+			assert.NoError(t, env.idxer.Stop(ctx))
+		}
+	})(env.idxer)
+
+	env.idxer.interval = time.Hour
+
+	env.idxer.Start(ctx)
+	require.NoError(t, env.idxer.Wait(ctx))
+
+	require.Eventually(t, func() bool {
+		return env.idxer.State() == Stopped
+	}, time.Second, time.Millisecond)
+
+	env.idxer.stateMu.Lock()
+	defer env.idxer.stateMu.Unlock()
+
+	assert.False(t, env.idxer.notifying)
+	assert.Equal(t, []transition{
+		{from: Created, to: Running},
+		{from: Running, to: Stopping},
+		{from: Stopping, to: Stopped},
+	}, transitions)
+}
+
+func TestIndexer_Restart_canceledRun(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	env.idxer.interval = time.Hour
+
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	env.idxer.Start(runCtx)
+	cancelRun()
+
+	ctx := context.Background()
+	require.ErrorIs(t, env.idxer.Wait(ctx), context.Canceled)
+
+	assert.ErrorIs(t, env.idxer.Restart(ctx), ErrRunContextDone)
+	assert.Equal(t, Stopped, env.idxer.State())
+}
@@ -0,0 +1,188 @@
+package indexer
+
+import (
+	"errors"
+	"math"
+
+	"github.com/sschiz/indexer/ticker"
+)
+
+var (
+	ErrOutlier          = errors.New("outlier")
+	ErrInvalidThreshold = errors.New("invalid threshold")
+)
+
+// minOutlierSamples is the minimum number of samples of a ticker to look for outliers.
+const minOutlierSamples = 3
+
+// OutlierFilter detects outlying prices of a ticker collected at a tick.
+type OutlierFilter interface {
+	// Outliers reports which samples are outliers.
+	Outliers(samples []Sample) []bool
+}
+
+// Exclusion describes a sample excluded from the index.
+type Exclusion struct {
+	Ticker ticker.Ticker
+	Sample Sample
+	Reason error
+}
+
+// WithOutlierFilter sets filter which excludes outlying prices before aggregation.
+// Tickers with less than three prices at a tick are not filtered,
+// and all prices are kept if the filter considers every one an outlier.
+func WithOutlierFilter(f OutlierFilter) Option {
+	return func(i *Indexer) {
+		i.outlierFilter = f
+	}
+}
+
+// WithExclusionHandler sets handler which is called for each excluded sample.
+func WithExclusionHandler(handle func(Exclusion)) Option {
+	return func(i *Indexer) {
+		i.handleExclusion = handle
+	}
+}
+
+// MADFilter considers outliers samples which deviate from the median
+// more than threshold times the median absolute deviation.
+// If it is zero, e.g. most sources report the same price, the mean absolute deviation is used instead,
+// so a small difference from the agreed price is not an outlier.
+// Both the median and the deviation are weighted by weights of sample sources.
+type MADFilter struct {
+	threshold ticker.Decimal
+}
+
+// NewMADFilter returns new MADFilter instance.
+// Threshold must be positive, 3 is a common choice.
+func NewMADFilter(threshold float64) (*MADFilter, error) {
+	if threshold <= 0 || math.IsNaN(threshold) || math.IsInf(threshold, 0) {
+		return nil, ErrInvalidThreshold
+	}
+
+	return &MADFilter{threshold: ticker.NewDecimalFromFloat(threshold)}, nil
+}
+
+// Outliers reports which samples are outliers.
+func (f *MADFilter) Outliers(samples []Sample) []bool {
+	median := medianOf(samples)
+
+	deviations := make([]Sample, len(samples))
+	for i, s := range samples {
+		deviations[i] = Sample{Price: s.Price.Sub(median).Abs(), Weight: s.Weight}
+	}
+
+	scale := medianOf(deviations)
+	if scale.IsZero() {
+		var mean Mean
+		for _, d := range deviations {
+			mean.Add(d)
+		}
+
+		scale, _ = mean.Value()
+	}
+
+	limit := scale.Mul(f.threshold)
+
+	outliers := make([]bool, len(samples))
+	for i, d := range deviations {
+		outliers[i] = d.Price.Cmp(limit) > 0
+	}
+
+	return outliers
+}
+
+// PercentFilter considers outliers samples which deviate from the median
+// more than the given percent of it. The median is weighted by weights of sample sources.
+type PercentFilter struct {
+	ratio ticker.Decimal
+}
+
+// NewPercentFilter returns new PercentFilter instance.
+// Percent must be positive.
+func NewPercentFilter(percent float64) (*PercentFilter, error) {
+	if percent <= 0 || math.IsNaN(percent) || math.IsInf(percent, 0) {
+		return nil, ErrInvalidThreshold
+	}
+
+	return &PercentFilter{
+		ratio: ticker.NewDecimalFromFloat(percent).Quo(ticker.NewDecimal(1, 2)),
+	}, nil
+}
+
+// Outliers reports which samples are outliers.
+func (f *PercentFilter) Outliers(samples []Sample) []bool {
+	median := medianOf(samples)
+	limit := median.Abs().Mul(f.ratio)
+
+	outliers := make([]bool, len(samples))
+	for i, s := range samples {
+		outliers[i] = s.Price.Sub(median).Abs().Cmp(limit) > 0
+	}
+
+	return outliers
+}
+
+// excludeOutliers removes outlying samples.
+func (i *Indexer) excludeOutliers(samples map[ticker.Ticker][]Sample) {
+	if i.outlierFilter == nil {
+		return
+	}
+
+	for tk, ss := range samples {
+		if len(ss) < minOutlierSamples {
+			continue
+		}
+
+		outliers := i.outlierFilter.Outliers(ss)
+
+		kept := make([]Sample, 0, len(ss))
+		excluded := make([]Sample, 0)
+
+		for j, s := range ss {
+			if j < len(outliers) && outliers[j] {
+				excluded = append(excluded, s)
+				continue
+			}
+
+			kept = append(kept, s)
+		}
+
+		if len(kept) == 0 {
+			continue
+		}
+
+		samples[tk] = kept
+
+		for _, s := range excluded {
+			i.exclude(tk, s, ErrOutlier)
+		}
+	}
+}
+
+func (i *Indexer) exclude(tk ticker.Ticker, s Sample, reason error) {
+	if i.handleDetailed != nil {
+		i.constituents[tk] = append(i.constituents[tk], constituent(s, reason))
+	}
+
+	if i.handleExclusion == nil {
+		return
+	}
+
+	i.handleExclusion(Exclusion{
+		Ticker: tk,
+		Sample: s,
+		Reason: reason,
+	})
+}
+
+func medianOf(samples []Sample) ticker.Decimal {
+	var m Median
+	for _, s := range samples {
+		m.Add(s)
+	}
+
+	v, _ := m.Value()
+
+	return v
+}
@@ -0,0 +1,147 @@
+package indexer
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func samplesOf(prices ...float64) []Sample {
+	samples := make([]Sample, 0, len(prices))
+	for _, p := range prices {
+		samples = append(samples, Sample{Price: dec(p)})
+	}
+
+	return samples
+}
+
+func TestNewMADFilter(t *testing.T) {
+	for _, threshold := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		got, err := NewMADFilter(threshold)
+		require.Nil(t, got)
+		assert.ErrorIs(t, err, ErrInvalidThreshold)
+	}
+}
+
+func TestNewPercentFilter(t *testing.T) {
+	for _, percent := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		got, err := NewPercentFilter(percent)
+		require.Nil(t, got)
+		assert.ErrorIs(t, err, ErrInvalidThreshold)
+	}
+}
+
+func TestOutlierFilters(t *testing.T) {
+	mad, err := NewMADFilter(3)
+	require.NoError(t, err)
+
+	percent, err := NewPercentFilter(5)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		filter  OutlierFilter
+		samples []Sample
+		want    []bool
+	}{
+		{
+			name:    "mad",
+			filter:  mad,
+			samples: samplesOf(100, 101, 99, 102, 150),
+			want:    []bool{false, false, false, false, true},
+		},
+		{
+			name:    "mad no outliers",
+			filter:  mad,
+			samples: samplesOf(100, 101, 99),
+			want:    []bool{false, false, false},
+		},
+		{
+			name:    "mad zero deviation",
+			filter:  mad,
+			samples: samplesOf(100, 100, 100.01),
+			want:    []bool{false, false, false},
+		},
+		{
+			name:    "mad zero deviation spread",
+			filter:  mad,
+			samples: samplesOf(100, 100, 100, 100.5, 99.5),
+			want:    []bool{false, false, false, false, false},
+		},
+		{
+			name:    "mad zero deviation outlier",
+			filter:  mad,
+			samples: samplesOf(100, 100, 100, 100, 1000),
+			want:    []bool{false, false, false, false, true},
+		},
+		{
+			name:   "mad weighted",
+			filter: mad,
+			samples: []Sample{
+				{Price: dec(100), Weight: dec(3)},
+				{Price: dec(101)},
+				{Price: dec(102)},
+				{Price: dec(110)},
+			},
+			want: []bool{false, false, false, true},
+		},
+		{
+			name:    "percent",
+			filter:  percent,
+			samples: samplesOf(100, 101, 99, 110),
+			want:    []bool{false, false, false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Outliers(tt.samples))
+		})
+	}
+}
+
+func TestIndexer_index_outliers(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	filter, err := NewPercentFilter(10)
+	require.NoError(t, err)
+
+	var excluded []Exclusion
+	WithOutlierFilter(filter)(env.idxer)
+	WithExclusionHandler(func(e Exclusion) {
+		excluded = append(excluded, e)
+	})(env.idxer)
+
+	var got []ticker.Price
+	env.idxer.handle = func(tp ticker.Price) {
+		got = append(got, tp)
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+
+	env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "100"},
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "1000"},
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "102"},
+		{Ticker: ethUSDTicker, Time: now, Price: "1"},
+		{Ticker: ethUSDTicker, Time: now, Price: "10"},
+	}, nil)
+
+	require.NoError(t, env.idxer.index(ctx, now))
+
+	assert.ElementsMatch(t, []ticker.Price{
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "101"},
+		{Ticker: ethUSDTicker, Time: now, Price: "5.5"},
+	}, got)
+
+	require.Len(t, excluded, 1)
+	assert.Equal(t, ticker.BTCUSDTicker, excluded[0].Ticker)
+	assert.Equal(t, "1000", excluded[0].Sample.Price.String())
+	assert.ErrorIs(t, excluded[0].Reason, ErrOutlier)
+}
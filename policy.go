@@ -0,0 +1,126 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrInvalidErrorPolicy = errors.New("invalid error policy")
+
+// ErrorPolicy defines how Indexer handles errors of a tick.
+type ErrorPolicy int
+
+const (
+	// FailFast stops Indexer on the first error. It is used by default.
+	FailFast ErrorPolicy = iota
+	// SkipTick discards the failed tick and continues with the next one.
+	SkipTick
+	// SkipTicker quarantines tickers with invalid prices like WithQuarantine,
+	// other errors discard the failed tick.
+	SkipTicker
+	// Retry retries the failed tick with exponential backoff
+	// and stops Indexer if all attempts fail.
+	// Stop interrupts the backoff, Indexer then stops with the error of the last attempt.
+	Retry
+)
+
+// ErrorHandler is called for each error of a tick, including errors which do not stop Indexer.
+type ErrorHandler func(error)
+
+// WithErrorPolicy sets policy of handling errors of a tick.
+// Use WithRetry to set Retry.
+func WithErrorPolicy(p ErrorPolicy) Option {
+	return func(i *Indexer) {
+		i.errorPolicy = p
+	}
+}
+
+// WithRetry sets Retry policy which retries the failed tick up to retries times.
+// Backoff is the delay before the first retry, it doubles after each one.
+func WithRetry(retries int, backoff time.Duration) Option {
+	return func(i *Indexer) {
+		i.errorPolicy = Retry
+		i.retries = retries
+		i.backoff = backoff
+	}
+}
+
+// WithErrorHandler sets handler of tick errors.
+func WithErrorHandler(handle ErrorHandler) Option {
+	return func(i *Indexer) {
+		i.handleError = handle
+	}
+}
+
+func (i *Indexer) validPolicy() bool {
+	switch i.errorPolicy {
+	case FailFast, SkipTick, SkipTicker:
+		return true
+	case Retry:
+		return i.retries > 0 && i.backoff > 0
+	default:
+		return false
+	}
+}
+
+// tick indexes at t according to the error policy.
+// It returns error only if Indexer has to stop.
+func (i *Indexer) tick(ctx context.Context, t time.Time) error {
+	backoff := i.backoff
+
+	for attempt := 0; ; attempt++ {
+		err := i.index(ctx, t)
+		if err == nil {
+			return nil
+		}
+
+		i.onError(err)
+
+		switch i.errorPolicy {
+		case SkipTick, SkipTicker:
+			return nil
+		case Retry:
+			if attempt >= i.retries {
+				return err
+			}
+
+			switch serr := sleep(ctx, i.done, backoff); {
+			case errors.Is(serr, errStopped):
+				return err
+			case serr != nil:
+				return serr
+			}
+
+			backoff *= 2
+		default:
+			return err
+		}
+	}
+}
+
+func (i *Indexer) onError(err error) {
+	if i.handleError != nil {
+		i.handleError(err)
+	}
+}
+
+// sleep waits for d and returns errStopped if stop is closed, even at the same time.
+func sleep(ctx context.Context, stop <-chan struct{}, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		select {
+		case <-stop:
+			return errStopped
+		default:
+			return nil
+		}
+	case <-stop:
+		return errStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
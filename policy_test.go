@@ -0,0 +1,178 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIndexer_errorPolicy(t *testing.T) {
+	for _, opt := range []Option{
+		WithErrorPolicy(ErrorPolicy(-1)),
+		WithErrorPolicy(Retry),
+		WithRetry(0, time.Second),
+		WithRetry(1, 0),
+	} {
+		env := tearUp(t)
+
+		got, err := NewIndexer(env.collecter, env.idxer.handle, time.Minute, opt)
+		require.Nil(t, got)
+		assert.ErrorIs(t, err, ErrInvalidErrorPolicy)
+
+		tearDown(env)
+	}
+}
+
+func TestIndexer_tick(t *testing.T) {
+	anyErr := errors.New("any error")
+
+	tests := []struct {
+		name    string
+		opt     Option
+		calls   int
+		wantErr error
+		errs    int
+	}{
+		{
+			name:    "fail fast",
+			opt:     WithErrorPolicy(FailFast),
+			calls:   1,
+			wantErr: anyErr,
+			errs:    1,
+		},
+		{
+			name:  "skip tick",
+			opt:   WithErrorPolicy(SkipTick),
+			calls: 1,
+			errs:  1,
+		},
+		{
+			name:    "retry exhausted",
+			opt:     WithRetry(2, time.Millisecond),
+			calls:   3,
+			wantErr: anyErr,
+			errs:    3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tearUp(t)
+			defer tearDown(env)
+
+			tt.opt(env.idxer)
+
+			var errs []error
+			WithErrorHandler(func(err error) {
+				errs = append(errs, err)
+			})(env.idxer)
+
+			ctx := context.Background()
+			env.collecter.EXPECT().Collect(ctx).Return(nil, anyErr).Times(tt.calls)
+
+			err := env.idxer.tick(ctx, time.Now())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Len(t, errs, tt.errs)
+		})
+	}
+
+	t.Run("retry succeeded", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		WithRetry(3, time.Millisecond)(env.idxer)
+
+		var got []ticker.Price
+		env.idxer.handle = func(tp ticker.Price) {
+			got = append(got, tp)
+		}
+
+		ctx := context.Background()
+		now := time.Now()
+
+		gomock.InOrder(
+			env.collecter.EXPECT().Collect(ctx).Return(nil, anyErr),
+			env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+				{Ticker: ticker.BTCUSDTicker, Time: now, Price: "1"},
+			}, nil),
+		)
+
+		require.NoError(t, env.idxer.tick(ctx, now))
+		assert.Equal(t, []ticker.Price{{Ticker: ticker.BTCUSDTicker, Time: now, Price: "1"}}, got)
+	})
+
+	t.Run("retry canceled", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		WithRetry(3, time.Hour)(env.idxer)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		env.collecter.EXPECT().Collect(ctx).DoAndReturn(func(context.Context) ([]*ticker.Price, error) {
+			cancel()
+			return nil, anyErr
+		})
+
+		assert.ErrorIs(t, env.idxer.tick(ctx, time.Now()), context.Canceled)
+	})
+
+	t.Run("retry stopped", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		WithRetry(3, time.Hour)(env.idxer)
+
+		ctx := context.Background()
+		env.collecter.EXPECT().Collect(ctx).DoAndReturn(func(context.Context) ([]*ticker.Price, error) {
+			close(env.idxer.done)
+			return nil, anyErr
+		})
+
+		assert.ErrorIs(t, env.idxer.tick(ctx, time.Now()), anyErr)
+	})
+
+	t.Run("skip ticker", func(t *testing.T) {
+		env := tearUp(t)
+
+		var errs []error
+		idxer, err := NewIndexer(env.collecter, env.idxer.handle, time.Minute,
+			WithErrorPolicy(SkipTicker),
+			WithErrorHandler(func(err error) {
+				errs = append(errs, err)
+			}),
+		)
+		require.NoError(t, err)
+
+		env.idxer = idxer
+		defer tearDown(env)
+
+		var got []ticker.Price
+		idxer.handle = func(tp ticker.Price) {
+			got = append(got, tp)
+		}
+
+		ctx := context.Background()
+		now := time.Now()
+
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "invalid"},
+			{Ticker: ethUSDTicker, Time: now, Price: "2"},
+		}, nil)
+
+		require.NoError(t, idxer.tick(ctx, now))
+		assert.Equal(t, []ticker.Price{{Ticker: ethUSDTicker, Time: now, Price: "2"}}, got)
+
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], ErrInvalidPrice)
+	})
+}
@@ -0,0 +1,124 @@
+package indexer
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule policy")
+
+// SchedulePolicy defines what Indexer does with ticks which fire while the previous one is running.
+// Ticks never overlap and run in order regardless of the policy.
+type SchedulePolicy int
+
+const (
+	// SkipMissed drops ticks which fire while the previous one is running. It is used by default.
+	SkipMissed SchedulePolicy = iota
+	// Coalesce runs a single tick after the running one at the time of the latest missed tick.
+	Coalesce
+	// Queue queues missed ticks up to the bound set by WithTickQueue, ticks beyond it are dropped.
+	Queue
+)
+
+// TickLag describes delay of a tick.
+type TickLag struct {
+	Time    time.Time     // time the tick was scheduled at
+	Lag     time.Duration // delay between the scheduled time and the start of the tick
+	Skipped int           // number of ticks dropped since the previous tick
+}
+
+// TickLagHandler is called before each tick.
+type TickLagHandler func(TickLag)
+
+// WithSchedulePolicy sets policy of ticks which fire while the previous one is running.
+// Use WithTickQueue to set Queue.
+func WithSchedulePolicy(p SchedulePolicy) Option {
+	return func(i *Indexer) {
+		i.schedulePolicy = p
+	}
+}
+
+// WithTickQueue sets Queue policy with the given bound of queued ticks.
+func WithTickQueue(size int) Option {
+	return func(i *Indexer) {
+		i.schedulePolicy = Queue
+		i.queueSize = size
+	}
+}
+
+// WithTickLagHandler sets handler of tick lag.
+func WithTickLagHandler(handle TickLagHandler) Option {
+	return func(i *Indexer) {
+		i.handleLag = handle
+	}
+}
+
+func (i *Indexer) validSchedule() bool {
+	switch i.schedulePolicy {
+	case SkipMissed, Coalesce:
+		return true
+	case Queue:
+		return i.queueSize > 0
+	default:
+		return false
+	}
+}
+
+type scheduledTick struct {
+	time    time.Time
+	skipped int
+}
+
+// scheduler passes ticks to the worker according to the schedule policy.
+type scheduler struct {
+	policy SchedulePolicy
+	ticks  chan scheduledTick
+	missed int // ticks dropped since the last scheduled one
+}
+
+func (i *Indexer) newScheduler() *scheduler {
+	var size int
+
+	switch i.schedulePolicy {
+	case Coalesce:
+		size = 1
+	case Queue:
+		size = i.queueSize
+	}
+
+	return &scheduler{
+		policy: i.schedulePolicy,
+		ticks:  make(chan scheduledTick, size),
+	}
+}
+
+// schedule passes tick to the worker without blocking.
+func (s *scheduler) schedule(t time.Time) {
+	if s.policy == Coalesce {
+		select {
+		case pending := <-s.ticks:
+			s.missed += pending.skipped + 1
+		default:
+		}
+	}
+
+	select {
+	case s.ticks <- scheduledTick{time: t, skipped: s.missed}:
+		s.missed = 0
+	default:
+		s.missed++
+	}
+}
+
+// lag reports lag of the tick.
+func (i *Indexer) lag(t scheduledTick) {
+	if i.handleLag == nil {
+		return
+	}
+
+	i.handleLag(TickLag{
+		Time:    t.time,
+		Lag:     time.Since(t.time),
+		Skipped: t.skipped,
+	})
+}
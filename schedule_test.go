@@ -0,0 +1,151 @@
+package indexer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestNewIndexer_schedule(t *testing.T) {
+	for _, opt := range []Option{
+		WithSchedulePolicy(SchedulePolicy(-1)),
+		WithSchedulePolicy(Queue),
+		WithTickQueue(0),
+	} {
+		env := tearUp(t)
+
+		got, err := NewIndexer(env.collecter, env.idxer.handle, time.Minute, opt)
+		require.Nil(t, got)
+		assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+		tearDown(env)
+	}
+}
+
+func TestScheduler_schedule(t *testing.T) {
+	now := time.Now()
+	at := func(n int) time.Time {
+		return now.Add(time.Duration(n) * time.Second)
+	}
+
+	tests := []struct {
+		name   string
+		opt    Option
+		want   []scheduledTick
+		missed int
+	}{
+		{
+			name:   "skip missed",
+			opt:    WithSchedulePolicy(SkipMissed),
+			want:   []scheduledTick{},
+			missed: 3,
+		},
+		{
+			name: "coalesce",
+			opt:  WithSchedulePolicy(Coalesce),
+			want: []scheduledTick{
+				{time: at(2), skipped: 2},
+			},
+		},
+		{
+			name: "queue",
+			opt:  WithTickQueue(2),
+			want: []scheduledTick{
+				{time: at(0)},
+				{time: at(1)},
+			},
+			missed: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tearUp(t)
+			defer tearDown(env)
+
+			tt.opt(env.idxer)
+
+			s := env.idxer.newScheduler()
+			for n := 0; n < 3; n++ {
+				s.schedule(at(n))
+			}
+
+			got := make([]scheduledTick, 0)
+			for len(s.ticks) > 0 {
+				got = append(got, <-s.ticks)
+			}
+
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.missed, s.missed)
+		})
+	}
+}
+
+func TestIndexer_start_slow(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	env.idxer.interval = time.Millisecond
+
+	var (
+		mu   sync.Mutex
+		lags []TickLag
+	)
+
+	WithTickLagHandler(func(l TickLag) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		lags = append(lags, l)
+	})(env.idxer)
+
+	running := atomic.NewInt32(0)
+	overlapped := atomic.NewBool(false)
+
+	env.collecter.EXPECT().Collect(gomock.Any()).DoAndReturn(func(context.Context) ([]*ticker.Price, error) {
+		if running.Inc() > 1 {
+			overlapped.Store(true)
+		}
+		defer running.Dec()
+
+		time.Sleep(10 * time.Millisecond)
+
+		return nil, nil
+	}).MinTimes(1)
+
+	ctx := context.Background()
+	env.idxer.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(lags) >= 3
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, env.idxer.Stop(ctx))
+	require.NoError(t, env.idxer.Wait(ctx))
+
+	assert.False(t, overlapped.Load())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	skipped := 0
+	for j, l := range lags {
+		if j > 0 {
+			assert.True(t, l.Time.After(lags[j-1].Time))
+		}
+
+		assert.GreaterOrEqual(t, l.Lag, time.Duration(0))
+		skipped += l.Skipped
+	}
+
+	assert.Positive(t, skipped)
+}
@@ -0,0 +1,55 @@
+package indexer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sschiz/indexer/ticker"
+)
+
+var ErrStalePrice = errors.New("stale price")
+
+// WithMaxAge discards prices which time is older than maxAge at the tick.
+// If every price of a ticker is stale, the index of the ticker is published
+// with Stale flag set and the last known value, empty if there is none.
+// Stale prices are excluded with ErrStalePrice.
+// Prices without time are never stale.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(i *Indexer) {
+		i.maxAge = maxAge
+	}
+}
+
+// dropStale removes stale samples and returns tickers which have only stale ones.
+func (i *Indexer) dropStale(samples map[ticker.Ticker][]Sample, t time.Time) map[ticker.Ticker]struct{} {
+	stale := make(map[ticker.Ticker]struct{})
+	if i.maxAge <= 0 {
+		return stale
+	}
+
+	for tk, ss := range samples {
+		fresh := ss[:0]
+		for _, s := range ss {
+			if s.Time.IsZero() || t.Sub(s.Time) <= i.maxAge {
+				fresh = append(fresh, s)
+				continue
+			}
+
+			i.exclude(tk, s, ErrStalePrice)
+		}
+
+		if len(fresh) == 0 {
+			stale[tk] = struct{}{}
+			delete(samples, tk)
+
+			// Make sure the stale index is published.
+			i.aggregator(tk)
+
+			continue
+		}
+
+		samples[tk] = fresh
+	}
+
+	return stale
+}
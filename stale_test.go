@@ -0,0 +1,119 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexer_index_stale(t *testing.T) {
+	tests := []struct {
+		name   string
+		window Window
+		want   []ticker.Price
+	}{
+		{
+			name:   "cumulative",
+			window: CumulativeWindow,
+			want: []ticker.Price{
+				{Ticker: ticker.BTCUSDTicker, Price: "3"},
+				{Ticker: ethUSDTicker, Price: "3", Stale: true},
+			},
+		},
+		{
+			name:   "tumbling",
+			window: TumblingWindow,
+			want: []ticker.Price{
+				{Ticker: ticker.BTCUSDTicker, Price: "4"},
+				{Ticker: ethUSDTicker, Price: "3", Stale: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tearUp(t)
+			defer tearDown(env)
+
+			WithWindow(tt.window)(env.idxer)
+			WithMaxAge(time.Minute)(env.idxer)
+
+			var got []ticker.Price
+			env.idxer.handle = func(tp ticker.Price) {
+				got = append(got, tp)
+			}
+
+			ctx := context.Background()
+			now := time.Now()
+			next := now.Add(time.Minute)
+
+			gomock.InOrder(
+				env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+					{Ticker: ticker.BTCUSDTicker, Time: now, Price: "2"},
+					{Ticker: ethUSDTicker, Time: now, Price: "3"},
+				}, nil),
+				env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+					{Ticker: ticker.BTCUSDTicker, Time: next, Price: "4"},
+					{Ticker: ticker.BTCUSDTicker, Time: now.Add(-10 * time.Minute), Price: "100"},
+					{Ticker: ethUSDTicker, Time: now.Add(-10 * time.Minute), Price: "5"},
+				}, nil),
+			)
+
+			require.NoError(t, env.idxer.index(ctx, now))
+			require.Len(t, got, 2)
+
+			got = nil
+
+			require.NoError(t, env.idxer.index(ctx, next))
+
+			for i := range tt.want {
+				tt.want[i].Time = next
+			}
+
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func TestIndexer_index_stale_neverPublished(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	WithMaxAge(time.Minute)(env.idxer)
+
+	var got []ticker.Price
+	env.idxer.handle = func(tp ticker.Price) {
+		got = append(got, tp)
+	}
+
+	var excluded []Constituent
+	WithDetailedHandler(func(tp ticker.Price, cs []Constituent) {
+		if tp.Ticker == ethUSDTicker {
+			excluded = cs
+		}
+	})(env.idxer)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "2"},
+		{Ticker: ethUSDTicker, Time: now.Add(-10 * time.Minute), Price: "5", Source: "binance"},
+	}, nil)
+
+	require.NoError(t, env.idxer.index(ctx, now))
+
+	assert.ElementsMatch(t, []ticker.Price{
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "2"},
+		{Ticker: ethUSDTicker, Time: now, Stale: true},
+	}, got)
+
+	require.Len(t, excluded, 1)
+	assert.Equal(t, "binance", excluded[0].Source)
+	assert.True(t, excluded[0].Excluded)
+	assert.ErrorIs(t, excluded[0].Reason, ErrStalePrice)
+}
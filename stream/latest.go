@@ -0,0 +1,145 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sschiz/indexer/ticker"
+)
+
+var (
+	ErrInvalidStream = errors.New("invalid stream")
+	ErrNoPrice       = errors.New("no price")
+)
+
+const (
+	minBackoff = 10 * time.Millisecond
+	maxBackoff = time.Second
+)
+
+// LatestStream caches the most recent price of the underlying stream,
+// so Get never blocks and a quiet or failing source keeps its last price.
+type LatestStream struct {
+	mu       sync.Mutex
+	price    *ticker.Price
+	received time.Time
+	err      error // the most recent error of the underlying stream
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewLatestStream returns new LatestStream instance.
+// The underlying stream is consumed in background until ctx is done, Close is called
+// or the stream returns ErrClosed. Errors and empty results are retried with exponential backoff.
+func NewLatestStream(ctx context.Context, src Stream) (*LatestStream, error) {
+	if src == nil {
+		return nil, ErrInvalidStream
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	s := &LatestStream{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go s.consume(ctx, src)
+
+	return s, nil
+}
+
+// Get returns the most recent price immediately.
+// ErrNoPrice is returned if nothing has been received yet,
+// StreamCollecter treats it as missing data. Errors of the underlying stream are returned by Err.
+func (s *LatestStream) Get(_ context.Context) (*ticker.Price, error) {
+	price, _, err := s.Latest()
+	return price, err
+}
+
+// Latest is like Get but also returns age of the price,
+// i.e. time elapsed since it has been received.
+func (s *LatestStream) Latest() (*ticker.Price, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.price == nil {
+		return nil, 0, ErrNoPrice
+	}
+
+	price := *s.price
+
+	return &price, time.Since(s.received), nil
+}
+
+// Err returns the most recent error of the underlying stream.
+// It is reset when a price is received.
+func (s *LatestStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Close stops consuming the underlying stream.
+func (s *LatestStream) Close() {
+	s.cancel()
+	<-s.done
+}
+
+func (s *LatestStream) consume(ctx context.Context, src Stream) {
+	defer close(s.done)
+
+	backoff := minBackoff
+
+	for {
+		price, err := src.Get(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil && price != nil {
+			s.mu.Lock()
+			s.price = price
+			s.received = time.Now()
+			s.err = nil
+			s.mu.Unlock()
+
+			backoff = minBackoff
+
+			continue
+		}
+
+		if err != nil {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+		}
+
+		if errors.Is(err, ErrClosed) {
+			return
+		}
+
+		if sleep(ctx, backoff) != nil {
+			return
+		}
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,149 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestNewLatestStream(t *testing.T) {
+	got, err := NewLatestStream(context.Background(), nil)
+	require.Nil(t, got)
+	assert.ErrorIs(t, err, ErrInvalidStream)
+}
+
+func TestLatestStream_Get(t *testing.T) {
+	errs := make(chan error)
+	tick := make(chan ticker.Price)
+
+	src, err := NewChanStream(tick, errs)
+	require.NoError(t, err)
+
+	stream, err := NewLatestStream(context.Background(), src)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	price, err := stream.Get(context.Background())
+	require.Nil(t, price)
+	require.ErrorIs(t, err, ErrNoPrice)
+
+	tick <- ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "1"}
+	tick <- ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "2"}
+
+	require.Eventually(t, func() bool {
+		price, err := stream.Get(context.Background())
+		return err == nil && price.Price == "2"
+	}, time.Second, time.Millisecond)
+
+	expected := errors.New("any error")
+	errs <- expected
+
+	require.Eventually(t, func() bool {
+		return errors.Is(stream.Err(), expected)
+	}, time.Second, time.Millisecond)
+
+	// Error does not hide the cached price.
+	price, age, err := stream.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, "2", price.Price)
+	assert.GreaterOrEqual(t, age, time.Duration(0))
+
+	tick <- ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "3"}
+
+	require.Eventually(t, func() bool {
+		price, err := stream.Get(context.Background())
+		return err == nil && price.Price == "3"
+	}, time.Second, time.Millisecond)
+	assert.NoError(t, stream.Err())
+}
+
+func TestLatestStream_Close(t *testing.T) {
+	src, err := NewChanStream(make(chan ticker.Price), make(chan error))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := NewLatestStream(ctx, src)
+	require.NoError(t, err)
+
+	stream.Close()
+
+	select {
+	case <-stream.done:
+	default:
+		t.Fatal("stream is not closed")
+	}
+}
+
+type countingStream struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (s *countingStream) Get(_ context.Context) (*ticker.Price, error) {
+	s.calls.Inc()
+	return nil, s.err
+}
+
+func TestLatestStream_backoff(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "error", err: errors.New("any error")},
+		{name: "no price"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &countingStream{err: tt.err}
+
+			stream, err := NewLatestStream(context.Background(), src)
+			require.NoError(t, err)
+
+			time.Sleep(100 * time.Millisecond)
+			stream.Close()
+
+			// 10ms, 20ms, 40ms, ... backoff allows only a few attempts.
+			assert.LessOrEqual(t, src.calls.Load(), int32(6))
+
+			price, err := stream.Get(context.Background())
+			require.Nil(t, price)
+			assert.ErrorIs(t, err, ErrNoPrice)
+			assert.Equal(t, tt.err, stream.Err())
+		})
+	}
+}
+
+func TestLatestStream_closed(t *testing.T) {
+	tick := make(chan ticker.Price)
+
+	src, err := NewChanStream(tick, make(chan error))
+	require.NoError(t, err)
+
+	stream, err := NewLatestStream(context.Background(), src)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	tick <- ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "1"}
+	close(tick)
+
+	select {
+	case <-stream.done:
+	case <-time.After(time.Second):
+		t.Fatal("stream is still consumed")
+	}
+
+	require.ErrorIs(t, stream.Err(), ErrClosed)
+
+	price, err := stream.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "1", price.Price)
+}
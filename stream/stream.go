@@ -3,43 +3,148 @@ package stream
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/sschiz/indexer/ticker"
 )
 
-var ErrInvalidChannel = errors.New("invalid channel")
+var (
+	ErrInvalidChannel = errors.New("invalid channel")
+	ErrClosed         = errors.New("stream closed")
+)
 
 // Stream streams ticker price.
 type Stream interface {
 	Get(ctx context.Context) (*ticker.Price, error)
 }
 
+// Drainer is implemented by streams which can return all available prices at once.
+type Drainer interface {
+	// Drain returns all prices available on the stream which time is not after until.
+	// It blocks until at least one price is available like Get.
+	Drain(ctx context.Context, until time.Time) ([]*ticker.Price, error)
+}
+
 // ChanStream streams ticker price using channels.
 type ChanStream struct {
-	errors <-chan error
-	ticker <-chan ticker.Price
+	errors  <-chan error
+	ticker  <-chan ticker.Price
+	pending *ticker.Price // price received by Drain after its until
+	err     error         // error received by Drain after some prices
+	source  string
+}
+
+// Option configures ChanStream.
+type Option func(*ChanStream)
+
+// WithSource sets Source of prices the stream receives without one.
+func WithSource(name string) Option {
+	return func(s *ChanStream) {
+		s.source = name
+	}
 }
 
 // NewChanStream returns new ChanStream instance.
-func NewChanStream(t <-chan ticker.Price, errs <-chan error) (*ChanStream, error) {
+func NewChanStream(t <-chan ticker.Price, errs <-chan error, opts ...Option) (*ChanStream, error) {
 	if t == nil || errs == nil {
 		return nil, ErrInvalidChannel
 	}
 
-	return &ChanStream{
+	s := &ChanStream{
 		errors: errs,
 		ticker: t,
-	}, nil
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
 }
 
 // Get returns incoming TickerPrice.
+// ErrClosed is returned once the price channel is closed.
 func (s *ChanStream) Get(ctx context.Context) (*ticker.Price, error) {
-	select {
-	case price := <-s.ticker:
-		return &price, nil
-	case err := <-s.errors:
+	if s.pending != nil {
+		price := s.pending
+		s.pending = nil
+
+		return price, nil
+	}
+
+	if err := s.err; err != nil {
+		s.err = nil
+
+		return nil, err
+	}
+
+	for {
+		select {
+		case price, ok := <-s.ticker:
+			if !ok {
+				return nil, ErrClosed
+			}
+
+			return s.receive(price), nil
+		case err, ok := <-s.errors:
+			if !ok {
+				// closed error channel must not stop prices.
+				s.errors = nil
+				continue
+			}
+
+			return nil, err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Drain returns all buffered prices which time is not after until.
+// The first price is awaited like in Get, the rest are read without blocking.
+// Price which time is after until is returned by the next call,
+// as well as error received after some prices.
+func (s *ChanStream) Drain(ctx context.Context, until time.Time) ([]*ticker.Price, error) {
+	price, err := s.Get(ctx)
+	if err != nil {
 		return nil, err
-	case <-ctx.Done():
-		return nil, ctx.Err()
 	}
+
+	prices := []*ticker.Price{price}
+
+	for {
+		select {
+		case price, ok := <-s.ticker:
+			if !ok {
+				s.err = ErrClosed
+				return prices, nil
+			}
+
+			if price.Time.After(until) {
+				s.pending = s.receive(price)
+				return prices, nil
+			}
+
+			prices = append(prices, s.receive(price))
+		case err, ok := <-s.errors:
+			if !ok {
+				s.errors = nil
+				continue
+			}
+
+			s.err = err
+
+			return prices, nil
+		default:
+			return prices, nil
+		}
+	}
+}
+
+func (s *ChanStream) receive(price ticker.Price) *ticker.Price {
+	if price.Source == "" {
+		price.Source = s.source
+	}
+
+	return &price
 }
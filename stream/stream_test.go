@@ -3,6 +3,7 @@ package stream
 import (
 	"context"
 	"errors"
+	"strconv"
 	"testing"
 	"time"
 
@@ -100,4 +101,153 @@ func TestChanStream_Get(t *testing.T) {
 		require.Nil(t, price)
 		assert.ErrorIs(t, err, context.DeadlineExceeded)
 	})
+
+	t.Run("closed", func(t *testing.T) {
+		errs := make(chan error)
+		tick := make(chan ticker.Price, 1)
+
+		tick <- ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "1"}
+		close(tick)
+		close(errs)
+
+		stream, err := NewChanStream(tick, errs)
+		require.NoError(t, err)
+
+		price, err := stream.Get(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "1", price.Price)
+
+		for i := 0; i < 2; i++ {
+			price, err = stream.Get(context.Background())
+			require.Nil(t, price)
+			assert.ErrorIs(t, err, ErrClosed)
+		}
+	})
+}
+
+func TestChanStream_source(t *testing.T) {
+	errs := make(chan error)
+	tick := make(chan ticker.Price, 3)
+
+	tick <- ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "1"}
+	tick <- ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "2", Source: "feed"}
+	tick <- ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "3"}
+
+	stream, err := NewChanStream(tick, errs, WithSource("binance"))
+	require.NoError(t, err)
+
+	price, err := stream.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "binance", price.Source)
+
+	prices, err := stream.Drain(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Len(t, prices, 2)
+	assert.Equal(t, "feed", prices[0].Source)
+	assert.Equal(t, "binance", prices[1].Source)
+}
+
+func TestChanStream_Drain(t *testing.T) {
+	t.Run("drained", func(t *testing.T) {
+		now := time.Now()
+
+		errs := make(chan error)
+		tick := make(chan ticker.Price, 4)
+
+		for i := -2; i <= 1; i++ {
+			tick <- ticker.Price{
+				Ticker: ticker.BTCUSDTicker,
+				Time:   now.Add(time.Duration(i) * time.Second),
+				Price:  strconv.Itoa(i),
+			}
+		}
+
+		stream, err := NewChanStream(tick, errs)
+		require.NoError(t, err)
+
+		prices, err := stream.Drain(context.Background(), now)
+		require.NoError(t, err)
+		require.Len(t, prices, 3)
+		assert.Equal(t, "-2", prices[0].Price)
+		assert.Equal(t, "0", prices[2].Price)
+
+		// Price after until is returned next time.
+		price, err := stream.Get(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "1", price.Price)
+	})
+
+	t.Run("error returned", func(t *testing.T) {
+		errs := make(chan error, 1)
+		tick := make(chan ticker.Price)
+
+		expected := errors.New("any error")
+		errs <- expected
+
+		stream, err := NewChanStream(tick, errs)
+		require.NoError(t, err)
+
+		prices, err := stream.Drain(context.Background(), time.Now())
+		require.Nil(t, prices)
+		assert.ErrorIs(t, err, expected)
+	})
+
+	t.Run("error after prices", func(t *testing.T) {
+		expected := errors.New("any error")
+
+		// Channels are selected randomly, so repeat to cover the error arriving after prices.
+		for n := 0; n < 100; n++ {
+			errs := make(chan error, 1)
+			tick := make(chan ticker.Price, 2)
+
+			tick <- ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "1"}
+			tick <- ticker.Price{Ticker: ticker.BTCUSDTicker, Price: "2"}
+			errs <- expected
+
+			stream, err := NewChanStream(tick, errs)
+			require.NoError(t, err)
+
+			var (
+				got    []string
+				failed int
+			)
+
+			for {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+				prices, err := stream.Drain(ctx, time.Now())
+				cancel()
+
+				if errors.Is(err, context.DeadlineExceeded) {
+					break
+				}
+
+				if err != nil {
+					require.ErrorIs(t, err, expected)
+					require.Nil(t, prices)
+					failed++
+
+					continue
+				}
+
+				for _, p := range prices {
+					got = append(got, p.Price)
+				}
+			}
+
+			require.Equal(t, []string{"1", "2"}, got)
+			require.Equal(t, 1, failed)
+		}
+	})
+
+	t.Run("DeadlineExceeded returned", func(t *testing.T) {
+		stream, err := NewChanStream(make(chan ticker.Price), make(chan error))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+		defer cancel()
+
+		prices, err := stream.Drain(ctx, time.Now())
+		require.Nil(t, prices)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
 }
@@ -0,0 +1,313 @@
+package ticker
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode defines how Decimal is rounded to a scale.
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds to the nearest neighbor, ties are rounded to the even one.
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to the nearest neighbor, ties are rounded away from zero.
+	RoundHalfUp
+	// RoundTruncate rounds toward zero.
+	RoundTruncate
+)
+
+// DefaultScale is a number of fractional digits
+// String uses for decimals without finite representation.
+const DefaultScale = 18
+
+const (
+	maxExponent = 1000
+	decimalBase = 10
+)
+
+// Decimal is an arbitrary-precision decimal number.
+// Arithmetic operations are exact, except Quo which result may have
+// no finite decimal representation until it is rounded.
+// Zero value is 0. Decimal is immutable, so it is safe to copy.
+type Decimal struct {
+	rat *big.Rat
+}
+
+// NewDecimal returns value * 10^exp.
+func NewDecimal(value int64, exp int32) Decimal {
+	r := new(big.Rat).SetInt64(value)
+	return Decimal{rat: r.Mul(r, pow10(exp))}
+}
+
+// NewDecimalFromFloat returns exact value of f.
+// It panics if f is NaN or infinite.
+func NewDecimalFromFloat(f float64) Decimal {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		panic("ticker: NewDecimalFromFloat of non-finite value")
+	}
+
+	return Decimal{rat: new(big.Rat).SetFloat64(f)}
+}
+
+// ParseDecimal parses decimal string, e.g. "-12.2", "0.5", "1e3".
+// NaN, infinities and fractions are rejected with strconv.ErrSyntax.
+// Exponents beyond ±1000 are rejected with strconv.ErrRange.
+func ParseDecimal(s string) (Decimal, error) {
+	exp, ok := scanDecimal(s)
+	if !ok {
+		return Decimal{}, &strconv.NumError{Func: "ParseDecimal", Num: s, Err: strconv.ErrSyntax}
+	}
+
+	if exp > maxExponent || exp < -maxExponent {
+		return Decimal{}, &strconv.NumError{Func: "ParseDecimal", Num: s, Err: strconv.ErrRange}
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Decimal{}, &strconv.NumError{Func: "ParseDecimal", Num: s, Err: strconv.ErrSyntax}
+	}
+
+	return Decimal{rat: r}, nil
+}
+
+// Add returns d + e.
+func (d Decimal) Add(e Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Add(d.r(), e.r())}
+}
+
+// Sub returns d - e.
+func (d Decimal) Sub(e Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Sub(d.r(), e.r())}
+}
+
+// Mul returns d * e.
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Mul(d.r(), e.r())}
+}
+
+// Quo returns d / e.
+// It panics if e is zero.
+func (d Decimal) Quo(e Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Quo(d.r(), e.r())}
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{rat: new(big.Rat).Neg(d.r())}
+}
+
+// Abs returns |d|.
+func (d Decimal) Abs() Decimal {
+	return Decimal{rat: new(big.Rat).Abs(d.r())}
+}
+
+// Cmp compares d and e and returns -1 if d < e, 0 if d == e and +1 if d > e.
+func (d Decimal) Cmp(e Decimal) int {
+	return d.r().Cmp(e.r())
+}
+
+// Sign returns -1 if d < 0, 0 if d == 0 and +1 if d > 0.
+func (d Decimal) Sign() int {
+	return d.r().Sign()
+}
+
+// IsZero reports whether d is zero.
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Float64 returns the nearest float64 value of d.
+func (d Decimal) Float64() float64 {
+	f, _ := d.r().Float64()
+	return f
+}
+
+// Round returns d rounded to scale fractional digits.
+// Negative scale rounds to the left of the decimal point.
+func (d Decimal) Round(scale int32, mode RoundingMode) Decimal {
+	p := pow10(scale)
+	x := new(big.Rat).Mul(d.r(), p)
+
+	q, rem := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	if rem.Sign() != 0 && roundAway(q, rem, x.Denom(), mode) {
+		if x.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+
+	r := new(big.Rat).SetInt(q)
+	return Decimal{rat: r.Quo(r, p)}
+}
+
+// StringFixed returns d rounded to scale fractional digits
+// with exactly scale digits after the decimal point.
+func (d Decimal) StringFixed(scale int32, mode RoundingMode) string {
+	digits := scale
+	if digits < 0 {
+		digits = 0
+	}
+
+	return d.Round(scale, mode).r().FloatString(int(digits))
+}
+
+// String returns the shortest exact representation of d.
+// Decimals without finite representation are rounded half to even to DefaultScale
+// fractional digits.
+func (d Decimal) String() string {
+	r := d.r()
+
+	if digits, ok := finiteDigits(r.Denom()); ok {
+		return r.FloatString(digits)
+	}
+
+	s := d.StringFixed(DefaultScale, RoundHalfEven)
+	s = strings.TrimRight(s, "0")
+
+	return strings.TrimSuffix(s, ".")
+}
+
+func (d Decimal) r() *big.Rat {
+	if d.rat == nil {
+		return new(big.Rat)
+	}
+
+	return d.rat
+}
+
+func roundAway(q, rem, den *big.Int, mode RoundingMode) bool {
+	if mode == RoundTruncate {
+		return false
+	}
+
+	half := new(big.Int).Abs(rem)
+	half.Lsh(half, 1)
+
+	switch half.Cmp(den) {
+	case 1:
+		return true
+	case 0:
+		return mode == RoundHalfUp || q.Bit(0) == 1
+	default:
+		return false
+	}
+}
+
+// finiteDigits returns number of fractional digits of a fraction with denominator den
+// if it has finite decimal representation.
+func finiteDigits(den *big.Int) (int, bool) {
+	var twos, fives int
+
+	d := new(big.Int).Set(den)
+	m := new(big.Int)
+
+	for d.Bit(0) == 0 && d.Sign() != 0 {
+		d.Rsh(d, 1)
+		twos++
+	}
+
+	five := big.NewInt(decimalBase / 2)
+	for {
+		q, r := new(big.Int).QuoRem(d, five, m)
+		if r.Sign() != 0 {
+			break
+		}
+
+		d = q
+		fives++
+	}
+
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+
+	if twos > fives {
+		return twos, true
+	}
+
+	return fives, true
+}
+
+func pow10(exp int32) *big.Rat {
+	n := int64(exp)
+	if n < 0 {
+		n = -n
+	}
+
+	p := new(big.Int).Exp(big.NewInt(decimalBase), big.NewInt(n), nil)
+	if exp < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), p)
+	}
+
+	return new(big.Rat).SetInt(p)
+}
+
+// scanDecimal reports whether s is [+-]digits[.digits][(e|E)[+-]digits]
+// and returns its exponent.
+func scanDecimal(s string) (int64, bool) {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+
+	digits := 0
+	for ; i < len(s) && isDigit(s[i]); i++ {
+		digits++
+	}
+
+	if i < len(s) && s[i] == '.' {
+		i++
+		for ; i < len(s) && isDigit(s[i]); i++ {
+			digits++
+		}
+	}
+
+	if digits == 0 {
+		return 0, false
+	}
+
+	if i == len(s) {
+		return 0, true
+	}
+
+	if s[i] != 'e' && s[i] != 'E' {
+		return 0, false
+	}
+
+	i++
+
+	neg := false
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		neg = s[i] == '-'
+		i++
+	}
+
+	if i == len(s) {
+		return 0, false
+	}
+
+	var exp int64
+	for ; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return 0, false
+		}
+
+		if exp <= maxExponent {
+			exp = exp*decimalBase + int64(s[i]-'0')
+		}
+	}
+
+	if neg {
+		exp = -exp
+	}
+
+	return exp, true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,119 @@
+package ticker
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParse(t *testing.T, s string) Decimal {
+	d, err := ParseDecimal(s)
+	require.NoError(t, err)
+
+	return d
+}
+
+func TestParseDecimal(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		tests := map[string]string{
+			"0":          "0",
+			"10":         "10",
+			"12.2":       "12.2",
+			"13.2345122": "13.2345122",
+			"-5":         "-5",
+			"+5.50":      "5.5",
+			".5":         "0.5",
+			"5.":         "5",
+			"1e3":        "1000",
+			"1.5E-2":     "0.015",
+		}
+		for in, want := range tests {
+			got, err := ParseDecimal(in)
+			require.NoError(t, err, in)
+			assert.Equal(t, want, got.String(), in)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, in := range []string{"", "-", ".", "NaN", "Inf", "-Inf", "1/3", "0x10", "1e", "1e+", "1.2.3", " 1"} {
+			got, err := ParseDecimal(in)
+			assert.ErrorIs(t, err, strconv.ErrSyntax, in)
+			assert.True(t, got.IsZero(), in)
+		}
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		for _, in := range []string{"1e1001", "1e-1001", "1e99999999999999999999"} {
+			_, err := ParseDecimal(in)
+			assert.ErrorIs(t, err, strconv.ErrRange, in)
+		}
+	})
+}
+
+func TestDecimal_arithmetic(t *testing.T) {
+	a := mustParse(t, "0.1")
+	b := mustParse(t, "0.2")
+
+	assert.Equal(t, "0.3", a.Add(b).String())
+	assert.Equal(t, "-0.1", a.Sub(b).String())
+	assert.Equal(t, "0.02", a.Mul(b).String())
+	assert.Equal(t, "0.5", a.Quo(b).String())
+	assert.Equal(t, "-0.1", a.Neg().String())
+	assert.Equal(t, "0.1", a.Neg().Abs().String())
+	assert.Equal(t, -1, a.Cmp(b))
+	assert.Equal(t, 1, b.Cmp(a))
+	assert.Equal(t, 0, a.Cmp(NewDecimal(1, -1)))
+	assert.Equal(t, "1200", NewDecimal(12, 2).String())
+	assert.Equal(t, "0.25", NewDecimalFromFloat(0.25).String())
+	assert.Equal(t, 0.1, a.Float64())
+
+	var zero Decimal
+	assert.True(t, zero.IsZero())
+	assert.Equal(t, "0", zero.String())
+	assert.Equal(t, "0.1", zero.Add(a).String())
+}
+
+func TestNewDecimalFromFloat(t *testing.T) {
+	assert.Panics(t, func() { NewDecimalFromFloat(math.NaN()) })
+	assert.Panics(t, func() { NewDecimalFromFloat(math.Inf(1)) })
+}
+
+func TestDecimal_String(t *testing.T) {
+	one := NewDecimal(1, 0)
+
+	assert.Equal(t, "0.333333333333333333", one.Quo(NewDecimal(3, 0)).String())
+	assert.Equal(t, "0.666666666666666667", NewDecimal(2, 0).Quo(NewDecimal(3, 0)).String())
+	assert.Equal(t, "0.125", one.Quo(NewDecimal(8, 0)).String())
+	assert.Equal(t, "-0.0625", one.Quo(NewDecimal(-16, 0)).String())
+}
+
+func TestDecimal_StringFixed(t *testing.T) {
+	tests := []struct {
+		in    string
+		scale int32
+		mode  RoundingMode
+		want  string
+	}{
+		{in: "2.5", scale: 0, mode: RoundHalfEven, want: "2"},
+		{in: "3.5", scale: 0, mode: RoundHalfEven, want: "4"},
+		{in: "-2.5", scale: 0, mode: RoundHalfEven, want: "-2"},
+		{in: "-3.5", scale: 0, mode: RoundHalfEven, want: "-4"},
+		{in: "2.5", scale: 0, mode: RoundHalfUp, want: "3"},
+		{in: "-2.5", scale: 0, mode: RoundHalfUp, want: "-3"},
+		{in: "2.9", scale: 0, mode: RoundTruncate, want: "2"},
+		{in: "-2.9", scale: 0, mode: RoundTruncate, want: "-2"},
+		{in: "1.005", scale: 2, mode: RoundHalfUp, want: "1.01"},
+		{in: "1.005", scale: 2, mode: RoundHalfEven, want: "1.00"},
+		{in: "1.006", scale: 2, mode: RoundHalfEven, want: "1.01"},
+		{in: "1.2", scale: 4, mode: RoundHalfEven, want: "1.2000"},
+		{in: "1250", scale: -2, mode: RoundHalfEven, want: "1200"},
+		{in: "1250", scale: -2, mode: RoundHalfUp, want: "1300"},
+	}
+	for _, tt := range tests {
+		got := mustParse(t, tt.in).StringFixed(tt.scale, tt.mode)
+		assert.Equal(t, tt.want, got, tt)
+	}
+}
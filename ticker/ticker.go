@@ -12,6 +12,20 @@ type Price struct {
 	Ticker Ticker
 	Time   time.Time
 	Price  string // decimal value. example: "0", "10", "12.2", "13.2345122"
+	Volume string // optional decimal value of traded quantity. empty if unknown
+	Stale  bool   // set on index which has no fresh prices
+	Source string // identifier of the source which produced the price. empty if unknown
+}
+
+// Candle is open, high, low and close prices of a ticker during an interval.
+type Candle struct {
+	Ticker Ticker
+	Time   time.Time // end of the interval
+	Open   string
+	High   string
+	Low    string
+	Close  string
+	Count  int // number of prices
 }
 
 type PriceStreamSubscriber interface {
@@ -0,0 +1,171 @@
+package indexer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sschiz/indexer/collecter"
+	"github.com/sschiz/indexer/ticker"
+)
+
+var (
+	ErrInvalidPrice     = errors.New("invalid price")
+	ErrNonPositivePrice = errors.New("non-positive price")
+	ErrPriceOutOfRange  = errors.New("price out of range")
+	ErrInvalidBounds    = errors.New("invalid price bounds")
+	ErrQuarantined      = errors.New("ticker quarantined")
+)
+
+// PriceError describes a price which failed validation.
+type PriceError struct {
+	Ticker ticker.Ticker
+	Price  *ticker.Price
+	Reason error // one of ErrInvalidPrice, ErrInvalidVolume, ErrNonPositivePrice, ErrPriceOutOfRange
+	Err    error // underlying error, e.g. parsing one
+}
+
+func (e *PriceError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v: %v", e.Ticker, e.Reason, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %v: %s", e.Ticker, e.Reason, e.Price.Price)
+}
+
+// Is reports whether target is the Reason of e.
+func (e *PriceError) Is(target error) bool {
+	return target == e.Reason
+}
+
+// Unwrap returns the underlying error.
+func (e *PriceError) Unwrap() error {
+	return e.Err
+}
+
+// Prices out of [minSafePrice, maxSafePrice] are rejected with ErrPriceOutOfRange regardless of bounds,
+// so every valid price fits float64 used by EMA.
+var (
+	minSafePrice = ticker.NewDecimal(1, -300)
+	maxSafePrice = ticker.NewDecimal(1, 300)
+)
+
+// WithPriceBounds rejects prices out of [min, max] with ErrPriceOutOfRange.
+// Zero max means there is no upper bound except the float64-safe one, 1e300.
+func WithPriceBounds(min, max ticker.Decimal) Option {
+	return func(i *Indexer) {
+		i.minPrice = min
+		i.maxPrice = max
+	}
+}
+
+// WithQuarantine makes Indexer quarantine tickers with invalid prices instead of stopping.
+// All prices of such a ticker are discarded for the current tick and its index is not published,
+// other tickers continue indexing. Handle is called for each invalid price, it may be nil.
+func WithQuarantine(handle func(*PriceError)) Option {
+	return func(i *Indexer) {
+		i.quarantine = true
+		i.onQuarantine = handle
+	}
+}
+
+// validate converts prices to samples grouped by ticker.
+// Tickers with invalid prices are returned separately if quarantine is enabled,
+// otherwise the first PriceError is returned.
+func (i *Indexer) validate(prices []*ticker.Price) (
+	valid map[ticker.Ticker][]Sample,
+	quarantined map[ticker.Ticker]struct{},
+	err error,
+) {
+	valid = make(map[ticker.Ticker][]Sample)
+	quarantined = make(map[ticker.Ticker]struct{})
+	rejected := make(map[*ticker.Price]*PriceError)
+
+	for _, price := range prices {
+		s, err := i.sample(price)
+		if err != nil {
+			if !i.quarantine {
+				return nil, nil, err
+			}
+
+			if i.onQuarantine != nil {
+				i.onQuarantine(err)
+			}
+
+			i.onError(err)
+
+			quarantined[price.Ticker] = struct{}{}
+			rejected[price] = err
+
+			continue
+		}
+
+		valid[price.Ticker] = append(valid[price.Ticker], s)
+	}
+
+	for tk := range quarantined {
+		delete(valid, tk)
+	}
+
+	if i.handleDetailed != nil {
+		i.excludeQuarantined(prices, quarantined, rejected)
+	}
+
+	return valid, quarantined, nil
+}
+
+func (i *Indexer) sample(price *ticker.Price) (Sample, *PriceError) {
+	p, err := ticker.ParseDecimal(price.Price)
+	if err != nil {
+		return Sample{}, newPriceError(price, ErrInvalidPrice, err)
+	}
+
+	if p.Sign() <= 0 {
+		return Sample{}, newPriceError(price, ErrNonPositivePrice, nil)
+	}
+
+	if p.Cmp(i.minPrice) < 0 || (!i.maxPrice.IsZero() && p.Cmp(i.maxPrice) > 0) ||
+		p.Cmp(minSafePrice) < 0 || p.Cmp(maxSafePrice) > 0 {
+		return Sample{}, newPriceError(price, ErrPriceOutOfRange, nil)
+	}
+
+	var volume ticker.Decimal
+	if price.Volume != "" {
+		volume, err = ticker.ParseDecimal(price.Volume)
+		if err != nil {
+			return Sample{}, newPriceError(price, ErrInvalidVolume, err)
+		}
+
+		if volume.Sign() < 0 {
+			return Sample{}, newPriceError(price, ErrInvalidVolume, nil)
+		}
+	}
+
+	return Sample{
+		Price:      p,
+		Time:       price.Time,
+		Volume:     volume,
+		Source:     price.Source,
+		Weight:     i.sourceWeight(price.Source),
+		raw:        price.Price,
+		zeroVolume: price.Volume != "" && volume.IsZero(),
+	}, nil
+}
+
+// sourceWeight returns weight of the source if collecter knows it, zero otherwise.
+func (i *Indexer) sourceWeight(source string) ticker.Decimal {
+	w, ok := i.collecter.(collecter.SourceWeigher)
+	if !ok || source == "" {
+		return ticker.Decimal{}
+	}
+
+	return w.SourceWeight(source)
+}
+
+func newPriceError(price *ticker.Price, reason, err error) *PriceError {
+	return &PriceError{
+		Ticker: price.Ticker,
+		Price:  price,
+		Reason: reason,
+		Err:    err,
+	}
+}
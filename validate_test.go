@@ -0,0 +1,205 @@
+package indexer
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/mock"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ethUSDTicker ticker.Ticker = "ETH_USD"
+
+func TestWithPriceBounds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clctr := mock.NewMockCollecter(ctrl)
+
+	for _, bounds := range [][2]int64{{-1, 0}, {10, 5}} {
+		got, err := NewIndexer(
+			clctr,
+			func(tp ticker.Price) { t.Log(tp) },
+			time.Minute,
+			WithPriceBounds(ticker.NewDecimal(bounds[0], 0), ticker.NewDecimal(bounds[1], 0)),
+		)
+
+		require.Nil(t, got)
+		assert.ErrorIs(t, err, ErrInvalidBounds)
+	}
+}
+
+func TestIndexer_sample(t *testing.T) {
+	tests := []struct {
+		name   string
+		price  ticker.Price
+		reason error
+		err    error
+	}{
+		{
+			name:   "NaN",
+			price:  ticker.Price{Price: "NaN"},
+			reason: ErrInvalidPrice,
+			err:    strconv.ErrSyntax,
+		},
+		{
+			name:   "Inf",
+			price:  ticker.Price{Price: "+Inf"},
+			reason: ErrInvalidPrice,
+			err:    strconv.ErrSyntax,
+		},
+		{
+			name:   "huge exponent",
+			price:  ticker.Price{Price: "1e100000"},
+			reason: ErrInvalidPrice,
+			err:    strconv.ErrRange,
+		},
+		{
+			name:   "negative",
+			price:  ticker.Price{Price: "-5"},
+			reason: ErrNonPositivePrice,
+		},
+		{
+			name:   "zero",
+			price:  ticker.Price{Price: "0"},
+			reason: ErrNonPositivePrice,
+		},
+		{
+			name:   "too low",
+			price:  ticker.Price{Price: "0.0001"},
+			reason: ErrPriceOutOfRange,
+		},
+		{
+			name:   "too high",
+			price:  ticker.Price{Price: "1000001"},
+			reason: ErrPriceOutOfRange,
+		},
+		{
+			name:   "absurdly high",
+			price:  ticker.Price{Price: "1e400"},
+			reason: ErrPriceOutOfRange,
+		},
+		{
+			name:   "invalid volume",
+			price:  ticker.Price{Price: "1", Volume: "x"},
+			reason: ErrInvalidVolume,
+			err:    strconv.ErrSyntax,
+		},
+		{
+			name:   "negative volume",
+			price:  ticker.Price{Price: "1", Volume: "-1"},
+			reason: ErrInvalidVolume,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tearUp(t)
+			defer tearDown(env)
+
+			WithPriceBounds(ticker.NewDecimal(1, -2), ticker.NewDecimal(1, 6))(env.idxer)
+
+			tt.price.Ticker = ticker.BTCUSDTicker
+
+			_, err := env.idxer.sample(&tt.price)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.reason)
+			assert.Equal(t, ticker.BTCUSDTicker, err.Ticker)
+
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestIndexer_index_validation(t *testing.T) {
+	now := time.Now()
+	prices := []*ticker.Price{
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "10"},
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "-5"},
+		{Ticker: ethUSDTicker, Time: now, Price: "2"},
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		ctx := context.Background()
+		env.collecter.EXPECT().Collect(ctx).Return(prices, nil)
+
+		err := env.idxer.index(ctx, now)
+
+		var priceErr *PriceError
+		require.ErrorAs(t, err, &priceErr)
+		assert.Equal(t, ticker.BTCUSDTicker, priceErr.Ticker)
+		assert.ErrorIs(t, err, ErrNonPositivePrice)
+	})
+
+	t.Run("zero volume with VWAP", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		WithAggregator(func() Aggregator { return NewVWAP() })(env.idxer)
+
+		var got []ticker.Price
+		env.idxer.handle = func(tp ticker.Price) {
+			got = append(got, tp)
+		}
+
+		ctx := context.Background()
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "10", Volume: "100"},
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "1000", Volume: "0"},
+		}, nil)
+
+		require.NoError(t, env.idxer.index(ctx, now))
+		assert.Equal(t, []ticker.Price{{Ticker: ticker.BTCUSDTicker, Time: now, Price: "10"}}, got)
+	})
+
+	t.Run("absurdly scaled with EMA", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		WithAggregator(func() Aggregator {
+			e, _ := NewEMA(time.Minute)
+			return e
+		})(env.idxer)
+
+		ctx := context.Background()
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "1e400"},
+		}, nil)
+
+		assert.ErrorIs(t, env.idxer.index(ctx, now), ErrPriceOutOfRange)
+	})
+
+	t.Run("quarantine", func(t *testing.T) {
+		env := tearUp(t)
+		defer tearDown(env)
+
+		var quarantined []*PriceError
+		WithQuarantine(func(err *PriceError) {
+			quarantined = append(quarantined, err)
+		})(env.idxer)
+
+		var got []ticker.Price
+		env.idxer.handle = func(tp ticker.Price) {
+			got = append(got, tp)
+		}
+
+		ctx := context.Background()
+		env.collecter.EXPECT().Collect(ctx).Return(prices, nil)
+
+		require.NoError(t, env.idxer.index(ctx, now))
+
+		assert.Equal(t, []ticker.Price{{Ticker: ethUSDTicker, Time: now, Price: "2"}}, got)
+		require.Len(t, quarantined, 1)
+		assert.Equal(t, prices[1], quarantined[0].Price)
+		assert.ErrorIs(t, quarantined[0], ErrNonPositivePrice)
+	})
+}
@@ -0,0 +1,115 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/collecter"
+	"github.com/sschiz/indexer/mock"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func weight(t *testing.T, s string) ticker.Decimal {
+	d, err := ticker.ParseDecimal(s)
+	require.NoError(t, err)
+
+	return d
+}
+
+// weighedCollecter is a collecter which knows weights of sources.
+type weighedCollecter struct {
+	*mock.MockCollecter
+	weights map[string]ticker.Decimal
+}
+
+func (c weighedCollecter) SourceWeight(source string) ticker.Decimal {
+	return c.weights[source]
+}
+
+// sourceWeights makes indexer of env weigh prices by weights of their sources.
+func sourceWeights(t *testing.T, env *testEnv, weights map[string]string) {
+	ws := make(map[string]ticker.Decimal, len(weights))
+	for source, w := range weights {
+		ws[source] = weight(t, w)
+	}
+
+	env.idxer.collecter = weighedCollecter{MockCollecter: env.collecter, weights: ws}
+}
+
+func TestIndexer_index_weighted(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	WithWindow(TumblingWindow)(env.idxer)
+	sourceWeights(t, env, map[string]string{"binance": "3", "kraken": "1", "coinbase": "0.1", "bitstamp": "0.3"})
+
+	var got []ticker.Price
+	env.idxer.handle = func(tp ticker.Price) {
+		got = append(got, tp)
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+
+	gomock.InOrder(
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "10", Source: "binance"},
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "20", Source: "kraken"},
+		}, nil),
+		// Missing source does not affect the index.
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "20", Source: "kraken"},
+		}, nil),
+		// Price of unknown source has weight of one.
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "20", Source: "kraken"},
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "40", Source: "huobi"},
+		}, nil),
+		// Fractional weights are exact.
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "100", Source: "coinbase"},
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "200", Source: "bitstamp"},
+		}, nil),
+	)
+
+	for j := 0; j < 4; j++ {
+		require.NoError(t, env.idxer.index(ctx, now))
+	}
+
+	assert.Equal(t, []ticker.Price{
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "12.5"},
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "20"},
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "30"},
+		{Ticker: ticker.BTCUSDTicker, Time: now, Price: "175"},
+	}, got)
+}
+
+func TestIndexer_index_streamCollecterWeights(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+
+	s1 := mock.NewMockStream(ctrl)
+	s1.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Ticker: ticker.BTCUSDTicker, Time: now, Price: "10"}, nil)
+
+	s2 := mock.NewMockStream(ctrl)
+	s2.EXPECT().Get(gomock.Any()).Return(&ticker.Price{Ticker: ticker.BTCUSDTicker, Time: now, Price: "20"}, nil)
+
+	clctr := collecter.NewStreamCollecter(nil,
+		collecter.WithStream(s1, collecter.StreamSource("binance"), collecter.StreamWeight(weight(t, "3"))),
+		collecter.WithStream(s2, collecter.StreamSource("kraken")),
+	)
+	require.NoError(t, clctr.Err())
+
+	var got []ticker.Price
+	idxer, err := NewIndexer(clctr, func(tp ticker.Price) { got = append(got, tp) }, time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, idxer.index(context.Background(), now))
+	assert.Equal(t, []ticker.Price{{Ticker: ticker.BTCUSDTicker, Time: now, Price: "12.5"}}, got)
+}
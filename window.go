@@ -0,0 +1,87 @@
+package indexer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sschiz/indexer/ticker"
+)
+
+var ErrInvalidWindow = errors.New("invalid window")
+
+// Window defines which samples the index covers.
+type Window int
+
+const (
+	// CumulativeWindow covers all samples since Indexer creation.
+	CumulativeWindow Window = iota
+	// TumblingWindow covers samples collected during the current interval only.
+	// Aggregators are reset after each tick.
+	TumblingWindow
+	// SlidingWindow covers samples which time is within the lookback duration from the current tick.
+	// Samples without time are considered to be received at the tick they were collected at.
+	SlidingWindow
+)
+
+// WithWindow sets window of the index.
+// CumulativeWindow is used by default.
+func WithWindow(w Window) Option {
+	return func(i *Indexer) {
+		i.window = w
+	}
+}
+
+// WithSlidingWindow sets SlidingWindow with the given lookback duration.
+func WithSlidingWindow(lookback time.Duration) Option {
+	return func(i *Indexer) {
+		i.window = SlidingWindow
+		i.lookback = lookback
+	}
+}
+
+func (w Window) valid() bool {
+	switch w {
+	case CumulativeWindow, TumblingWindow, SlidingWindow:
+		return true
+	default:
+		return false
+	}
+}
+
+// keep stores sample of the sliding window.
+func (i *Indexer) keep(tk ticker.Ticker, s Sample, t time.Time) {
+	if s.Time.IsZero() {
+		s.Time = t
+	}
+
+	i.samples[tk] = append(i.samples[tk], s)
+}
+
+// slide evicts samples which are older than the lookback duration
+// and refills aggregators with the remaining ones.
+func (i *Indexer) slide(t time.Time) {
+	cutoff := t.Add(-i.lookback)
+
+	for tk, samples := range i.samples {
+		kept := samples[:0]
+		for _, s := range samples {
+			if s.Time.After(cutoff) {
+				kept = append(kept, s)
+			}
+		}
+
+		i.aggregator(tk).Reset()
+		delete(i.contribs, tk)
+
+		if len(kept) == 0 {
+			delete(i.samples, tk)
+			continue
+		}
+
+		for _, s := range kept {
+			i.add(tk, s)
+		}
+
+		i.samples[tk] = kept
+	}
+}
@@ -0,0 +1,125 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sschiz/indexer/mock"
+	"github.com/sschiz/indexer/ticker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clctr := mock.NewMockCollecter(ctrl)
+
+	got, err := NewIndexer(
+		clctr,
+		func(tp ticker.Price) { t.Log(tp) },
+		time.Minute,
+		WithWindow(Window(-1)),
+	)
+
+	require.Nil(t, got)
+	assert.ErrorIs(t, err, ErrInvalidWindow)
+
+	got, err = NewIndexer(
+		clctr,
+		func(tp ticker.Price) { t.Log(tp) },
+		time.Minute,
+		WithSlidingWindow(0),
+	)
+
+	require.Nil(t, got)
+	assert.ErrorIs(t, err, ErrInvalidWindow)
+}
+
+func TestIndexer_index_window(t *testing.T) {
+	tests := []struct {
+		name   string
+		window Window
+		want   []string
+	}{
+		{
+			name:   "cumulative",
+			window: CumulativeWindow,
+			want:   []string{"2", "3"},
+		},
+		{
+			name:   "tumbling",
+			window: TumblingWindow,
+			want:   []string{"2", "4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tearUp(t)
+			defer tearDown(env)
+
+			WithWindow(tt.window)(env.idxer)
+
+			var got []string
+			env.idxer.handle = func(tp ticker.Price) {
+				got = append(got, tp.Price)
+			}
+
+			ctx := context.Background()
+			now := time.Now()
+
+			gomock.InOrder(
+				env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+					{Ticker: ticker.BTCUSDTicker, Time: now, Price: "2"},
+				}, nil),
+				env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+					{Ticker: ticker.BTCUSDTicker, Time: now, Price: "4"},
+				}, nil),
+			)
+
+			require.NoError(t, env.idxer.index(ctx, now))
+			require.NoError(t, env.idxer.index(ctx, now.Add(time.Minute)))
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIndexer_index_slidingWindow(t *testing.T) {
+	env := tearUp(t)
+	defer tearDown(env)
+
+	WithSlidingWindow(90 * time.Second)(env.idxer)
+
+	var got []string
+	env.idxer.handle = func(tp ticker.Price) {
+		got = append(got, tp.Price)
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+
+	gomock.InOrder(
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Time: now, Price: "2"},
+		}, nil),
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Price: "4"},
+		}, nil),
+		env.collecter.EXPECT().Collect(ctx).Return([]*ticker.Price{
+			{Ticker: ticker.BTCUSDTicker, Time: now.Add(2 * time.Minute), Price: "6"},
+		}, nil),
+		env.collecter.EXPECT().Collect(ctx).Return(nil, nil),
+	)
+
+	require.NoError(t, env.idxer.index(ctx, now))
+	require.NoError(t, env.idxer.index(ctx, now.Add(time.Minute)))
+	require.NoError(t, env.idxer.index(ctx, now.Add(2*time.Minute)))
+	require.NoError(t, env.idxer.index(ctx, now.Add(5*time.Minute)))
+
+	assert.Equal(t, []string{"2", "3", "5"}, got)
+	assert.Empty(t, env.idxer.samples)
+}